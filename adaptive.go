@@ -0,0 +1,241 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the adaptive rate limiting transport, which paces requests
+// according to the quota advertised by the server in rate limit response headers.
+package httpc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdaptiveRateLimitConfig configures a rate limiter that learns the remaining
+// quota from response headers and slows outgoing requests before the server
+// starts answering 429. It understands the X-RateLimit-Remaining/Reset headers,
+// the IETF RateLimit-Remaining/Reset headers, and the structured IETF RateLimit
+// header (for example `RateLimit: limit=100, remaining=20, reset=30`).
+type AdaptiveRateLimitConfig struct {
+	// Key derives the quota key from a request, since quotas are usually
+	// tracked per upstream. If nil, KeyByHost is used.
+	Key RateLimitKeyFunc
+
+	// MaxWait is the longest a request waits for quota. Requests that would
+	// need to wait longer fail with ErrRateLimited. If zero, one minute is used.
+	MaxWait time.Duration
+}
+
+// defaultAdaptiveMaxWait is used when AdaptiveRateLimitConfig.MaxWait is not set.
+const defaultAdaptiveMaxWait = time.Minute
+
+// defaultAdaptiveInterval spaces requests waiting for a quota reset when the
+// server never advertised any remaining quota to derive a pace from.
+const defaultAdaptiveInterval = 100 * time.Millisecond
+
+// quotaState is the quota last advertised by the server for one key.
+type quotaState struct {
+	remaining int
+	reset     time.Time
+	next      time.Time
+	interval  time.Duration
+}
+
+// quotaSlot is a request slot booked by reserve, so that it can be given back.
+type quotaSlot struct {
+	key      string
+	previous time.Time
+	next     time.Time
+	counted  bool
+}
+
+// adaptiveRateLimitTransport is an http.RoundTripper that spreads requests
+// evenly over the quota advertised by the server until its reset time.
+type adaptiveRateLimitTransport struct {
+	transport http.RoundTripper
+	config    AdaptiveRateLimitConfig
+
+	mu     sync.Mutex
+	quotas map[string]*quotaState
+	now    func() time.Time
+}
+
+// newAdaptiveRateLimitTransport creates an adaptiveRateLimitTransport.
+func newAdaptiveRateLimitTransport(rt http.RoundTripper, config AdaptiveRateLimitConfig) *adaptiveRateLimitTransport {
+	if config.Key == nil {
+		config.Key = KeyByHost()
+	}
+	if config.MaxWait <= 0 {
+		config.MaxWait = defaultAdaptiveMaxWait
+	}
+
+	return &adaptiveRateLimitTransport{
+		transport: rt,
+		config:    config,
+		quotas:    make(map[string]*quotaState),
+		now:       time.Now,
+	}
+}
+
+// RoundTrip implements http.RoundTripper by waiting until the learned quota
+// allows the request, sending it, and updating the quota from the response.
+func (t *adaptiveRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := t.config.Key(req)
+
+	delay, slot, err := t.reserve(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := sleepContext(req.Context(), delay); err != nil {
+		t.unreserve(slot)
+		return nil, err
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err == nil {
+		t.update(key, resp)
+	}
+	return resp, err
+}
+
+// reserve returns how long a request for key must wait and books its slot.
+// With quota left, requests are spaced evenly until the reset time. With no
+// quota left, they are spaced from the reset time on at the last pace derived
+// from the server quota, rather than all released together. A request that
+// would wait longer than MaxWait fails with ErrRateLimited without booking a slot.
+func (t *adaptiveRateLimitTransport) reserve(key string) (time.Duration, quotaSlot, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	q, ok := t.quotas[key]
+	if !ok {
+		return 0, quotaSlot{}, nil
+	}
+	if !now.Before(q.reset) && !now.Before(q.next) {
+		delete(t.quotas, key)
+		return 0, quotaSlot{}, nil
+	}
+
+	start := q.next
+	if start.Before(now) {
+		start = now
+	}
+
+	counted := q.remaining > 0 && start.Before(q.reset)
+	var next time.Time
+	if counted {
+		q.interval = q.reset.Sub(start) / time.Duration(q.remaining)
+		next = start.Add(q.interval)
+	} else {
+		if start.Before(q.reset) {
+			start = q.reset
+		}
+		interval := q.interval
+		if interval <= 0 {
+			interval = defaultAdaptiveInterval
+		}
+		next = start.Add(interval)
+	}
+
+	delay := start.Sub(now)
+	if delay > t.config.MaxWait {
+		return 0, quotaSlot{}, fmt.Errorf("%w: server quota resets in %v", ErrRateLimited, delay)
+	}
+
+	slot := quotaSlot{key: key, previous: q.next, next: next, counted: counted}
+	q.next = next
+	if counted {
+		q.remaining--
+	}
+	return delay, slot, nil
+}
+
+// unreserve gives back a slot booked by reserve for a request that will not
+// be sent. The pace is only rewound if no later request has been booked.
+func (t *adaptiveRateLimitTransport) unreserve(slot quotaSlot) {
+	if slot.next.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q, ok := t.quotas[slot.key]
+	if !ok {
+		return
+	}
+	if slot.counted {
+		q.remaining++
+	}
+	if q.next.Equal(slot.next) {
+		q.next = slot.previous
+	}
+}
+
+// update records the quota advertised by resp, if any.
+func (t *adaptiveRateLimitTransport) update(key string, resp *http.Response) {
+	now := t.now()
+	remaining, reset, ok := parseRateLimitHeaders(resp.Header, now)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q, exists := t.quotas[key]
+	if !exists {
+		q = &quotaState{}
+		t.quotas[key] = q
+	}
+	q.remaining = remaining
+	q.reset = reset
+}
+
+// parseRateLimitHeaders extracts the remaining quota and its reset time from
+// X-RateLimit-*, IETF RateLimit-* or the structured IETF RateLimit header.
+// It returns false if the headers do not carry both values.
+func parseRateLimitHeaders(header http.Header, now time.Time) (int, time.Time, bool) {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remaining, errRemaining := strconv.Atoi(strings.TrimSpace(header.Get(prefix + "Remaining")))
+		reset, errReset := strconv.ParseFloat(strings.TrimSpace(header.Get(prefix+"Reset")), 64)
+		if errRemaining == nil && errReset == nil {
+			return remaining, now.Add(rateLimitResetDelay(reset, now)), true
+		}
+	}
+
+	if value := header.Get("RateLimit"); value != "" {
+		return parseStructuredRateLimit(value, now)
+	}
+
+	return 0, time.Time{}, false
+}
+
+// parseStructuredRateLimit parses the structured IETF RateLimit header, in both
+// the `limit=100, remaining=20, reset=30` and the `"default";r=20;t=30` forms.
+func parseStructuredRateLimit(value string, now time.Time) (int, time.Time, bool) {
+	var remaining, reset string
+
+	for _, param := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		name, val, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			continue
+		}
+		switch strings.ToLower(name) {
+		case "remaining", "r":
+			remaining = val
+		case "reset", "t":
+			reset = val
+		}
+	}
+
+	r, errRemaining := strconv.Atoi(remaining)
+	t, errReset := strconv.ParseFloat(reset, 64)
+	if errRemaining != nil || errReset != nil {
+		return 0, time.Time{}, false
+	}
+
+	return r, now.Add(rateLimitResetDelay(t, now)), true
+}
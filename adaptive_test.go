@@ -0,0 +1,214 @@
+// Package httpc provides tests for adaptive rate limiting.
+// This file contains tests for parsing rate limit headers and for pacing
+// requests according to the quota advertised by the server.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		remaining int
+		reset     time.Time
+		ok        bool
+	}{
+		{"X-RateLimit delta", map[string]string{"X-RateLimit-Remaining": "5", "X-RateLimit-Reset": "10"}, 5, now.Add(10 * time.Second), true},
+		{"X-RateLimit timestamp", map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000030"}, 0, now.Add(30 * time.Second), true},
+		{"IETF fields", map[string]string{"RateLimit-Remaining": "7", "RateLimit-Reset": "3"}, 7, now.Add(3 * time.Second), true},
+		{"IETF structured", map[string]string{"RateLimit": "limit=100, remaining=20, reset=30"}, 20, now.Add(30 * time.Second), true},
+		{"IETF structured draft", map[string]string{"RateLimit": `"default";r=4;t=8`}, 4, now.Add(8 * time.Second), true},
+		{"Remaining only", map[string]string{"X-RateLimit-Remaining": "5"}, 0, time.Time{}, false},
+		{"No headers", nil, 0, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			for key, value := range tt.headers {
+				header.Set(key, value)
+			}
+
+			remaining, reset, ok := parseRateLimitHeaders(header, now)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if remaining != tt.remaining {
+				t.Errorf("remaining = %d, want %d", remaining, tt.remaining)
+			}
+			if !reset.Equal(tt.reset) {
+				t.Errorf("reset = %v, want %v", reset, tt.reset)
+			}
+		})
+	}
+}
+
+// reserveDelay books a slot for key and returns its delay.
+func reserveDelay(t *testing.T, transport *adaptiveRateLimitTransport, key string) time.Duration {
+	t.Helper()
+
+	delay, _, err := transport.reserve(key)
+	if err != nil {
+		t.Fatalf("reserve() failed: %v", err)
+	}
+	return delay
+}
+
+func TestAdaptiveRateLimit_Reserve(t *testing.T) {
+	now := time.Unix(1000, 0)
+	transport := newAdaptiveRateLimitTransport(defaultTransport(), AdaptiveRateLimitConfig{})
+	transport.now = func() time.Time { return now }
+
+	// Unknown key: no wait
+	if delay := reserveDelay(t, transport, "api"); delay != 0 {
+		t.Errorf("Expected no delay without quota info, got %v", delay)
+	}
+
+	// 4 requests left over the next 4 seconds: one per second
+	transport.quotas["api"] = &quotaState{remaining: 4, reset: now.Add(4 * time.Second)}
+
+	expected := []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second}
+	for i, want := range expected {
+		if got := reserveDelay(t, transport, "api"); got != want {
+			t.Errorf("request %d: expected delay %v, got %v", i+1, want, got)
+		}
+	}
+
+	// Quota exhausted: wait for the reset, then keep the same pace
+	if got := reserveDelay(t, transport, "api"); got != 4*time.Second {
+		t.Errorf("Expected to wait for reset, got %v", got)
+	}
+	if got := reserveDelay(t, transport, "api"); got != 5*time.Second {
+		t.Errorf("Expected the next waiter to be spaced after the reset, got %v", got)
+	}
+
+	// Once the booked slots have passed, the quota is forgotten
+	now = now.Add(6 * time.Second)
+	if got := reserveDelay(t, transport, "api"); got != 0 {
+		t.Errorf("Expected no delay after reset, got %v", got)
+	}
+}
+
+func TestAdaptiveRateLimit_ReserveMaxWait(t *testing.T) {
+	now := time.Unix(1000, 0)
+	transport := newAdaptiveRateLimitTransport(defaultTransport(), AdaptiveRateLimitConfig{MaxWait: time.Second})
+	transport.now = func() time.Time { return now }
+
+	transport.quotas["api"] = &quotaState{remaining: 0, reset: now.Add(time.Hour)}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := transport.reserve("api"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got %v", err)
+		}
+	}
+
+	// Rejected requests do not push the schedule further out
+	if q := transport.quotas["api"]; !q.next.IsZero() {
+		t.Errorf("Expected no slot to be booked, next is %v", q.next)
+	}
+}
+
+func TestAdaptiveRateLimit_Unreserve(t *testing.T) {
+	now := time.Unix(1000, 0)
+	transport := newAdaptiveRateLimitTransport(defaultTransport(), AdaptiveRateLimitConfig{})
+	transport.now = func() time.Time { return now }
+
+	transport.quotas["api"] = &quotaState{remaining: 4, reset: now.Add(4 * time.Second)}
+	reserveDelay(t, transport, "api")
+
+	_, slot, err := transport.reserve("api")
+	if err != nil {
+		t.Fatalf("reserve() failed: %v", err)
+	}
+	transport.unreserve(slot)
+
+	// The cancelled slot is handed to the next request
+	if q := transport.quotas["api"]; q.remaining != 3 {
+		t.Errorf("Expected the quota to be given back, got %d remaining", q.remaining)
+	}
+	if got := reserveDelay(t, transport, "api"); got != time.Second {
+		t.Errorf("Expected the cancelled slot to be reused, got %v", got)
+	}
+}
+
+func TestClient_WithAdaptiveRateLimit_CancelledWaitGivesBackSlot(t *testing.T) {
+	now := time.Unix(1000, 0)
+	transport := newAdaptiveRateLimitTransport(defaultTransport(), AdaptiveRateLimitConfig{})
+	transport.now = func() time.Time { return now }
+	client := NewClient(WithInterceptor(func(http.RoundTripper) http.RoundTripper { return transport }))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	key := KeyByHost()(httptest.NewRequest(http.MethodGet, server.URL, nil))
+	transport.quotas[key] = &quotaState{remaining: 1, reset: now.Add(time.Minute), next: now.Add(time.Second)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.NewRequest().Context(ctx).URL(server.URL).Do(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the wait to be cancelled, got %v", err)
+	}
+
+	if q := transport.quotas[key]; q.remaining != 1 || !q.next.Equal(now.Add(time.Second)) {
+		t.Errorf("Expected the slot to be given back, got %d remaining, next %v", q.remaining, q.next)
+	}
+}
+
+func TestClient_WithAdaptiveRateLimit_WaitsForReset(t *testing.T) {
+	var calls []time.Time
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, time.Now())
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(2-len(calls)))
+		w.Header().Set("X-RateLimit-Reset", "1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithAdaptiveRateLimit(AdaptiveRateLimitConfig{}))
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("Get() %d failed: %v", i+1, err)
+		}
+	}
+
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 calls, got %d", len(calls))
+	}
+
+	// The second response reports no quota left, so the third request waits ~1s
+	if gap := calls[2].Sub(calls[1]); gap < 900*time.Millisecond {
+		t.Errorf("Expected the third request to wait for the reset, gap was %v", gap)
+	}
+}
+
+func TestClient_WithAdaptiveRateLimit_MaxWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "3600")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithAdaptiveRateLimit(AdaptiveRateLimitConfig{MaxWait: time.Second}))
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the backoff strategies used by the retry transport.
+package httpc
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// BackoffStrategy computes how long to wait before the next retry attempt.
+// The attempt argument is zero-based: 0 is the wait before the first retry.
+// The prev argument is the delay returned for the previous attempt (zero for
+// the first retry), which allows strategies such as decorrelated jitter to
+// build on the previous wait.
+//
+// BackoffStrategy functions must be safe for concurrent use, since a single
+// RetryConfig is shared by all requests of a Client.
+//
+// Example:
+//
+//	config := httpc.DefaultRetryConfig()
+//	config.BackoffStrategy = httpc.FullJitterBackoff(100*time.Millisecond, 10*time.Second)
+type BackoffStrategy func(attempt int, prev time.Duration) time.Duration
+
+// ConstantBackoff returns a BackoffStrategy that always waits for delay.
+//
+// Example:
+//
+//	config.BackoffStrategy = httpc.ConstantBackoff(500 * time.Millisecond)
+func ConstantBackoff(delay time.Duration) BackoffStrategy {
+	return func(int, time.Duration) time.Duration {
+		return delay
+	}
+}
+
+// LinearBackoff returns a BackoffStrategy that waits base * (attempt + 1).
+// This is the schedule used when RetryConfig.BackoffStrategy is nil.
+//
+// Example:
+//
+//	config.BackoffStrategy = httpc.LinearBackoff(time.Second) // 1s, 2s, 3s, ...
+func LinearBackoff(base time.Duration) BackoffStrategy {
+	return func(attempt int, _ time.Duration) time.Duration {
+		return base * time.Duration(attempt+1)
+	}
+}
+
+// ExponentialBackoff returns a BackoffStrategy that waits base * 2^attempt,
+// capped at maxDelay. A maxDelay of zero or less disables the cap.
+//
+// Example:
+//
+//	config.BackoffStrategy = httpc.ExponentialBackoff(time.Second, 30*time.Second) // 1s, 2s, 4s, ...
+func ExponentialBackoff(base, maxDelay time.Duration) BackoffStrategy {
+	return func(attempt int, _ time.Duration) time.Duration {
+		return exponentialDelay(base, maxDelay, attempt)
+	}
+}
+
+// FullJitterBackoff returns a BackoffStrategy that waits a random duration
+// between zero and the capped exponential delay (base * 2^attempt).
+// Full jitter spreads retries from many clients evenly over time and is the
+// best default for avoiding synchronized retry storms.
+//
+// Example:
+//
+//	config.BackoffStrategy = httpc.FullJitterBackoff(100*time.Millisecond, 10*time.Second)
+func FullJitterBackoff(base, maxDelay time.Duration) BackoffStrategy {
+	return func(attempt int, _ time.Duration) time.Duration {
+		return randomDuration(0, exponentialDelay(base, maxDelay, attempt))
+	}
+}
+
+// EqualJitterBackoff returns a BackoffStrategy that waits half of the capped
+// exponential delay plus a random duration up to the other half.
+// It guarantees a minimum wait while still spreading retries apart.
+//
+// Example:
+//
+//	config.BackoffStrategy = httpc.EqualJitterBackoff(100*time.Millisecond, 10*time.Second)
+func EqualJitterBackoff(base, maxDelay time.Duration) BackoffStrategy {
+	return func(attempt int, _ time.Duration) time.Duration {
+		half := exponentialDelay(base, maxDelay, attempt) / 2
+		return half + randomDuration(0, half)
+	}
+}
+
+// DecorrelatedJitterBackoff returns a BackoffStrategy that waits a random
+// duration between base and three times the previous delay, capped at maxDelay.
+// A maxDelay of zero or less disables the cap.
+//
+// Example:
+//
+//	config.BackoffStrategy = httpc.DecorrelatedJitterBackoff(100*time.Millisecond, 10*time.Second)
+func DecorrelatedJitterBackoff(base, maxDelay time.Duration) BackoffStrategy {
+	return func(_ int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		delay := randomDuration(base, prev*3)
+		if maxDelay > 0 && delay > maxDelay {
+			delay = maxDelay
+		}
+		return delay
+	}
+}
+
+// exponentialDelay returns base * 2^attempt, capped at maxDelay when maxDelay is positive.
+// It guards against overflow for large attempt numbers.
+func exponentialDelay(base, maxDelay time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 0; i < attempt; i++ {
+		if delay > time.Duration(1<<62)/2 {
+			break
+		}
+		delay *= 2
+		if maxDelay > 0 && delay >= maxDelay {
+			break
+		}
+	}
+
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// randomDuration returns a random duration in the half-open range [lo, hi).
+// If hi is not greater than lo, lo is returned.
+func randomDuration(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rand.Int64N(int64(hi-lo)))
+}
@@ -0,0 +1,147 @@
+// Package httpc provides tests for retry backoff strategies.
+// This file contains tests for constant, linear, exponential and jittered backoff.
+package httpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	strategy := ConstantBackoff(100 * time.Millisecond)
+
+	for attempt := 0; attempt < 5; attempt++ {
+		if got := strategy(attempt, 0); got != 100*time.Millisecond {
+			t.Errorf("attempt %d: expected 100ms, got %v", attempt, got)
+		}
+	}
+}
+
+func TestLinearBackoff(t *testing.T) {
+	strategy := LinearBackoff(100 * time.Millisecond)
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for attempt, expected := range want {
+		if got := strategy(attempt, 0); got != expected {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, expected, got)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		max      time.Duration
+		attempt  int
+		expected time.Duration
+	}{
+		{"First attempt", 100 * time.Millisecond, time.Second, 0, 100 * time.Millisecond},
+		{"Second attempt", 100 * time.Millisecond, time.Second, 1, 200 * time.Millisecond},
+		{"Third attempt", 100 * time.Millisecond, time.Second, 2, 400 * time.Millisecond},
+		{"Capped", 100 * time.Millisecond, time.Second, 10, time.Second},
+		{"No cap", 100 * time.Millisecond, 0, 4, 1600 * time.Millisecond},
+		{"Huge attempt does not overflow", time.Second, 0, 200, exponentialDelay(time.Second, 0, 200)},
+		{"Zero base", 0, time.Second, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExponentialBackoff(tt.base, tt.max)(tt.attempt, 0)
+			if got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+			if got < 0 {
+				t.Errorf("expected non-negative delay, got %v", got)
+			}
+		})
+	}
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	strategy := FullJitterBackoff(100*time.Millisecond, time.Second)
+
+	for i := 0; i < 100; i++ {
+		got := strategy(2, 0)
+		if got < 0 || got >= 400*time.Millisecond {
+			t.Fatalf("expected delay in [0, 400ms), got %v", got)
+		}
+	}
+}
+
+func TestEqualJitterBackoff(t *testing.T) {
+	strategy := EqualJitterBackoff(100*time.Millisecond, time.Second)
+
+	for i := 0; i < 100; i++ {
+		got := strategy(2, 0)
+		if got < 200*time.Millisecond || got >= 400*time.Millisecond {
+			t.Fatalf("expected delay in [200ms, 400ms), got %v", got)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	strategy := DecorrelatedJitterBackoff(100*time.Millisecond, 500*time.Millisecond)
+
+	var prev time.Duration
+	for attempt := 0; attempt < 50; attempt++ {
+		got := strategy(attempt, prev)
+		if got < 100*time.Millisecond || got > 500*time.Millisecond {
+			t.Fatalf("attempt %d: expected delay in [100ms, 500ms], got %v", attempt, got)
+		}
+		prev = got
+	}
+}
+
+func TestRetryConfig_BackoffDelay_DefaultsToLinear(t *testing.T) {
+	config := RetryConfig{Backoff: 50 * time.Millisecond}
+
+	if got := config.backoffDelay(2, 0); got != 150*time.Millisecond {
+		t.Errorf("expected 150ms, got %v", got)
+	}
+}
+
+func TestClient_WithRetry_BackoffStrategy(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var attempts []int
+	config := RetryConfig{
+		MaxRetries: 3,
+		Backoff:    time.Hour,
+		RetryIf:    defaultRetryCondition,
+		BackoffStrategy: func(attempt int, prev time.Duration) time.Duration {
+			attempts = append(attempts, attempt)
+			return time.Millisecond
+		},
+	}
+
+	client := NewClient(WithRetry(config))
+
+	start := time.Now()
+	_, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	// The strategy should win over the (huge) linear Backoff
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected strategy delay to be used, took %v", elapsed)
+	}
+
+	if callCount.Load() != 4 {
+		t.Errorf("Expected 4 calls, got %d", callCount.Load())
+	}
+
+	if len(attempts) != 3 || attempts[0] != 0 || attempts[2] != 2 {
+		t.Errorf("Expected strategy to be called with attempts [0 1 2], got %v", attempts)
+	}
+}
@@ -0,0 +1,133 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the retry budget that caps retry amplification across a Client.
+package httpc
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryBudget limits the number of retries a Client may issue relative to
+// the number of requests it sends. Unlike MaxRetries, which bounds a single
+// request, a budget is shared by all requests of the Client configured through
+// WithRetry, so retries are shed automatically when the upstream is broadly
+// failing instead of multiplying the load.
+//
+// Within the sliding Window, retries are allowed while
+//
+//	retries < Ratio * requests + MinRetriesPerSecond * Window (in seconds)
+//
+// Example:
+//
+//	config := httpc.DefaultRetryConfig()
+//	config.Budget = &httpc.RetryBudget{
+//		Ratio:               0.1, // retries may add at most 10% extra load
+//		MinRetriesPerSecond: 5,
+//		Window:              10 * time.Second,
+//	}
+//	client := httpc.NewClient(httpc.WithRetry(*config))
+type RetryBudget struct {
+	// Ratio is the fraction of recent requests that may be retried (0.1 = 10%).
+	Ratio float64
+
+	// MinRetriesPerSecond is a floor of retries always allowed, so that
+	// low-traffic clients can still retry.
+	MinRetriesPerSecond int
+
+	// Window is the period over which requests and retries are counted.
+	// If zero, 10 seconds is used.
+	Window time.Duration
+}
+
+// DefaultRetryBudget returns a RetryBudget with sensible defaults:
+// - retries limited to 10% of recent requests
+// - a floor of 10 retries per second
+// - a 10 second window
+func DefaultRetryBudget() *RetryBudget {
+	return &RetryBudget{
+		Ratio:               0.1,
+		MinRetriesPerSecond: 10,
+		Window:              defaultRetryBudgetWindow,
+	}
+}
+
+// defaultRetryBudgetWindow is used when RetryBudget.Window is not set.
+const defaultRetryBudgetWindow = 10 * time.Second
+
+// budgetBucket holds the request and retry counts for one second of the window.
+type budgetBucket struct {
+	second   int64
+	requests int
+	retries  int
+}
+
+// retryBudget tracks requests and retries over a sliding window of one-second
+// buckets and decides whether another retry fits in the budget.
+// It is safe for concurrent use.
+type retryBudget struct {
+	mu      sync.Mutex
+	config  RetryBudget
+	buckets []budgetBucket
+	now     func() time.Time
+}
+
+// newRetryBudget creates the shared budget state for the given configuration.
+func newRetryBudget(config RetryBudget) *retryBudget {
+	if config.Window <= 0 {
+		config.Window = defaultRetryBudgetWindow
+	}
+
+	seconds := int((config.Window + time.Second - 1) / time.Second)
+
+	return &retryBudget{
+		config:  config,
+		buckets: make([]budgetBucket, seconds),
+		now:     time.Now,
+	}
+}
+
+// bucket returns the bucket for the current second, resetting it if it
+// still holds counts from an earlier pass over the ring.
+// The caller must hold b.mu.
+func (b *retryBudget) bucket() *budgetBucket {
+	second := b.now().Unix()
+	bucket := &b.buckets[int(second%int64(len(b.buckets)))]
+	if bucket.second != second {
+		*bucket = budgetBucket{second: second}
+	}
+	return bucket
+}
+
+// recordRequest counts a new logical request (not including its retries).
+func (b *retryBudget) recordRequest() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bucket().requests++
+}
+
+// tryRetry reports whether a retry is allowed and, if so, withdraws it from the budget.
+func (b *retryBudget) tryRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := b.bucket()
+	oldest := current.second - int64(len(b.buckets)) + 1
+
+	var requests, retries int
+	for _, bucket := range b.buckets {
+		if bucket.second >= oldest {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+
+	allowed := b.config.Ratio*float64(requests) +
+		float64(b.config.MinRetriesPerSecond)*float64(len(b.buckets))
+	if float64(retries) >= allowed {
+		return false
+	}
+
+	current.retries++
+	return true
+}
@@ -0,0 +1,114 @@
+// Package httpc provides tests for retry budgets.
+// This file contains tests for the sliding window accounting and for
+// budgets shared across the requests of a Client.
+package httpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDefaultRetryBudget(t *testing.T) {
+	budget := DefaultRetryBudget()
+
+	if budget.Ratio != 0.1 {
+		t.Errorf("Expected Ratio=0.1, got %v", budget.Ratio)
+	}
+
+	if budget.MinRetriesPerSecond != 10 {
+		t.Errorf("Expected MinRetriesPerSecond=10, got %d", budget.MinRetriesPerSecond)
+	}
+
+	if budget.Window != 10*time.Second {
+		t.Errorf("Expected Window=10s, got %v", budget.Window)
+	}
+}
+
+func TestRetryBudget_Ratio(t *testing.T) {
+	now := time.Unix(1000, 0)
+	budget := newRetryBudget(RetryBudget{Ratio: 0.5, Window: time.Second})
+	budget.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		budget.recordRequest()
+	}
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if budget.tryRetry() {
+			allowed++
+		}
+	}
+
+	if allowed != 5 {
+		t.Errorf("Expected 5 retries allowed (50%% of 10 requests), got %d", allowed)
+	}
+}
+
+func TestRetryBudget_MinRetriesPerSecond(t *testing.T) {
+	now := time.Unix(1000, 0)
+	budget := newRetryBudget(RetryBudget{MinRetriesPerSecond: 2, Window: 3 * time.Second})
+	budget.now = func() time.Time { return now }
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if budget.tryRetry() {
+			allowed++
+		}
+	}
+
+	if allowed != 6 {
+		t.Errorf("Expected 6 retries allowed (2/s over 3s), got %d", allowed)
+	}
+}
+
+func TestRetryBudget_WindowSlides(t *testing.T) {
+	now := time.Unix(1000, 0)
+	budget := newRetryBudget(RetryBudget{MinRetriesPerSecond: 1, Window: 2 * time.Second})
+	budget.now = func() time.Time { return now }
+
+	if !budget.tryRetry() || !budget.tryRetry() {
+		t.Fatal("Expected the first 2 retries to be allowed")
+	}
+	if budget.tryRetry() {
+		t.Fatal("Expected the budget to be exhausted")
+	}
+
+	// Once the old retries fall out of the window, the budget recovers
+	now = now.Add(2 * time.Second)
+	if !budget.tryRetry() {
+		t.Error("Expected retry to be allowed after the window slid")
+	}
+}
+
+func TestClient_WithRetry_BudgetSharedAcrossRequests(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+		Budget:     &RetryBudget{Ratio: 0.2, Window: time.Minute},
+	}))
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+	}
+
+	// Without a budget this would be 5 * (1 + 3) = 20 calls. With retries capped
+	// at 20% of requests, only a single retry fits across all 5 requests.
+	if got := callCount.Load(); got != 6 {
+		t.Errorf("Expected 6 calls (5 requests + 1 retry), got %d", got)
+	}
+}
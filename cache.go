@@ -0,0 +1,668 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the private HTTP cache (RFC 9111) transport and its
+// in-memory LRU storage.
+package httpc
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStorage stores encoded cache entries by key. Implementations must be
+// safe for concurrent use; they may evict entries at any time.
+type CacheStorage interface {
+	// Get returns the entry stored under key, if any.
+	Get(key string) ([]byte, bool)
+
+	// Set stores entry under key, replacing any previous entry.
+	Set(key string, entry []byte)
+
+	// Delete removes the entry stored under key, if any.
+	Delete(key string)
+}
+
+// CacheConfig configures WithCache.
+type CacheConfig struct {
+	// Storage holds the cached responses. If nil, an LRUCache of 1000 entries is used.
+	Storage CacheStorage
+
+	// MaxBodySize is the maximum size in bytes of a cached response body.
+	// Larger responses are passed through without being stored. If zero, 10 MB
+	// is used.
+	MaxBodySize int64
+}
+
+// Cache defaults used when CacheConfig fields are not set.
+const (
+	defaultCacheEntries     = 1000
+	defaultCacheMaxBodySize = 10 << 20
+
+	// heuristicFraction and maxHeuristicLifetime bound the freshness assigned
+	// to responses without explicit expiration, from their Last-Modified date
+	// (RFC 9111, Section 4.2.2).
+	heuristicFraction    = 10
+	maxHeuristicLifetime = 24 * time.Hour
+)
+
+// heuristicallyCacheable lists the status codes that may be cached without
+// explicit freshness information (RFC 9110, Section 15.1). 206 is omitted as
+// range requests are not cached.
+var heuristicallyCacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// cacheRecorderKey is the context key under which RequestBuilder.Do stores
+// the cacheRecorder for a request.
+type cacheRecorderKey struct{}
+
+// cacheRecorder reports to the Response how a request was served by the cache
+// or the revalidation transport. All methods are safe to call on a nil recorder.
+type cacheRecorder struct {
+	hit         atomic.Bool
+	revalidated atomic.Bool
+}
+
+// cacheRecorderFrom returns the recorder stored in ctx, or nil.
+func cacheRecorderFrom(ctx context.Context) *cacheRecorder {
+	recorder, _ := ctx.Value(cacheRecorderKey{}).(*cacheRecorder)
+	return recorder
+}
+
+// recordHit marks the request as served from the cache.
+func (r *cacheRecorder) recordHit() {
+	if r != nil {
+		r.hit.Store(true)
+	}
+}
+
+// fromCache reports whether the request was served from the cache.
+func (r *cacheRecorder) fromCache() bool {
+	return r != nil && r.hit.Load()
+}
+
+// recordRevalidated marks the request as answered with a 304 Not Modified.
+func (r *cacheRecorder) recordRevalidated() {
+	if r != nil {
+		r.revalidated.Store(true)
+	}
+}
+
+// wasRevalidated reports whether the request was answered with a 304 Not Modified.
+func (r *cacheRecorder) wasRevalidated() bool {
+	return r != nil && r.revalidated.Load()
+}
+
+// cacheEntry is a stored response with the metadata needed to compute its age
+// and to match it to requests.
+type cacheEntry struct {
+	StatusCode   int               `json:"status"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+	Vary         map[string]string `json:"vary,omitempty"`
+}
+
+// cacheControl holds parsed Cache-Control directives, with lowercase names.
+type cacheControl map[string]string
+
+// parseCacheControl parses the Cache-Control header values of h. A request
+// with "Pragma: no-cache" and no Cache-Control is treated as no-cache.
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, value := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	if len(cc) == 0 && strings.Contains(strings.ToLower(h.Get("Pragma")), "no-cache") {
+		cc["no-cache"] = ""
+	}
+	return cc
+}
+
+// has reports whether the directive is present.
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns the delta-seconds argument of the directive, if present and valid.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	arg, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// cacheTransport is an http.RoundTripper implementing a private HTTP cache.
+type cacheTransport struct {
+	transport http.RoundTripper
+	config    CacheConfig
+	now       func() time.Time
+}
+
+// newCacheTransport creates a cacheTransport, filling in configuration defaults.
+func newCacheTransport(rt http.RoundTripper, config CacheConfig) *cacheTransport {
+	if config.Storage == nil {
+		config.Storage = NewLRUCache(defaultCacheEntries)
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultCacheMaxBodySize
+	}
+	return &cacheTransport{transport: rt, config: config, now: time.Now}
+}
+
+// RoundTrip implements http.RoundTripper. GET requests are answered from the
+// cache while the stored response is fresh; stale responses with validators
+// are revalidated with a conditional request. Cacheable responses are stored
+// once their body has been read. Successful unsafe requests (POST, PUT, PATCH,
+// DELETE...) invalidate the stored responses of their URL.
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := t.transport.RoundTrip(req)
+		if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < http.StatusBadRequest {
+			t.invalidate(req, resp)
+		}
+		return resp, err
+	}
+
+	// Range and caller-driven conditional requests are not served from the cache
+	if req.Header.Get("Range") != "" || req.Header.Get("If-None-Match") != "" ||
+		req.Header.Get("If-Modified-Since") != "" {
+		return t.transport.RoundTrip(req)
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	key := cacheKey(req)
+	entry := t.load(key, req)
+
+	if entry != nil && !reqCC.has("no-cache") && t.usable(entry, reqCC) {
+		cacheRecorderFrom(req.Context()).recordHit()
+		return t.response(entry, req), nil
+	}
+	if reqCC.has("only-if-cached") {
+		return t.gatewayTimeout(req), nil
+	}
+
+	// Revalidate a stored response with its validators
+	outReq := req
+	if entry != nil {
+		if conditional := conditionalRequest(req, entry.Header); conditional != nil {
+			outReq = conditional
+		}
+	}
+
+	requestTime := t.now()
+	resp, err := t.transport.RoundTrip(outReq)
+	if err != nil {
+		return resp, err
+	}
+	responseTime := t.now()
+
+	if outReq != req && resp.StatusCode == http.StatusNotModified {
+		discardResponse(resp)
+		entry.update(resp.Header, requestTime, responseTime)
+		t.store(key, entry)
+		recorder := cacheRecorderFrom(req.Context())
+		recorder.recordHit()
+		recorder.recordRevalidated()
+		return t.response(entry, req), nil
+	}
+
+	if !t.storable(reqCC, resp) {
+		// The stored response was replaced by one that cannot be cached
+		if entry != nil && resp.StatusCode < http.StatusInternalServerError {
+			t.config.Storage.Delete(key)
+		}
+		return resp, nil
+	}
+	if resp.Body == nil || resp.ContentLength > t.config.MaxBodySize {
+		return resp, nil
+	}
+
+	entry = &cacheEntry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+		Vary:         varyValues(req, resp.Header),
+	}
+	storeOnComplete(resp, t.config.MaxBodySize, func(body []byte) {
+		entry.Body = body
+		t.store(key, entry)
+	})
+	return resp, nil
+}
+
+// storeOnComplete wraps the body of resp to call store with a copy of it once
+// it has been read to the end. Bodies larger than limit and bodies closed
+// before the end are not passed to store.
+func storeOnComplete(resp *http.Response, limit int64, store func(body []byte)) {
+	resp.Body = &cacheBody{ReadCloser: resp.Body, limit: limit, store: store}
+}
+
+// cacheBody buffers a response body as it is read and passes the buffer to
+// store once the body reaches EOF. Buffering stops for good once the body
+// grows past limit.
+type cacheBody struct {
+	io.ReadCloser
+	limit    int64
+	store    func(body []byte)
+	buf      bytes.Buffer
+	overflow bool
+	stored   bool
+}
+
+// Read reads from the underlying body and buffers what was read.
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.overflow {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.overflow && !b.stored {
+		b.stored = true
+		b.store(bytes.Clone(b.buf.Bytes()))
+	}
+	return n, err
+}
+
+// usable reports whether entry can be served without revalidation, given
+// the request directives max-age, min-fresh and max-stale.
+func (t *cacheTransport) usable(entry *cacheEntry, reqCC cacheControl) bool {
+	respCC := parseCacheControl(entry.Header)
+	if respCC.has("no-cache") {
+		return false
+	}
+
+	lifetime := entry.freshnessLifetime(respCC)
+	age := entry.currentAge(t.now())
+
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	if minFresh, ok := reqCC.seconds("min-fresh"); ok {
+		age += minFresh
+	}
+	if age < lifetime {
+		return true
+	}
+
+	// A stale response may be served if the request accepts it
+	if reqCC.has("max-stale") && !respCC.has("must-revalidate") {
+		maxStale, ok := reqCC.seconds("max-stale")
+		return !ok || age-lifetime <= maxStale
+	}
+	return false
+}
+
+// storable reports whether resp to a GET request may be stored
+// (RFC 9111, Section 3).
+func (t *cacheTransport) storable(reqCC cacheControl, resp *http.Response) bool {
+	respCC := parseCacheControl(resp.Header)
+	if reqCC.has("no-store") || respCC.has("no-store") {
+		return false
+	}
+	if resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+
+	if respCC.has("max-age") || resp.Header.Get("Expires") != "" {
+		return true
+	}
+
+	// Without explicit freshness, a response is only worth storing if it can
+	// be revalidated or is given a heuristic lifetime from Last-Modified
+	return hasValidators(resp.Header) &&
+		(heuristicallyCacheable[resp.StatusCode] || respCC.has("public") || respCC.has("private"))
+}
+
+// load returns the entry stored under key if it matches the Vary header
+// values of req, or nil.
+func (t *cacheTransport) load(key string, req *http.Request) *cacheEntry {
+	return loadCacheEntry(t.config.Storage, key, req)
+}
+
+// store encodes entry and saves it under key.
+func (t *cacheTransport) store(key string, entry *cacheEntry) {
+	storeCacheEntry(t.config.Storage, key, entry)
+}
+
+// loadCacheEntry returns the entry stored in storage under key if it matches
+// the Vary header values of req, or nil. Undecodable entries are removed.
+func loadCacheEntry(storage CacheStorage, key string, req *http.Request) *cacheEntry {
+	data, ok := storage.Get(key)
+	if !ok {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		storage.Delete(key)
+		return nil
+	}
+
+	for name, value := range entry.Vary {
+		if headerValue(req.Header, name) != value {
+			return nil
+		}
+	}
+	return &entry
+}
+
+// storeCacheEntry encodes entry and saves it in storage under key.
+func storeCacheEntry(storage CacheStorage, key string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	storage.Set(key, data)
+}
+
+// invalidate removes the stored responses of the target URL of req and of the
+// same-origin Location and Content-Location URLs of resp (RFC 9111, Section 4.4).
+func (t *cacheTransport) invalidate(req *http.Request, resp *http.Response) {
+	t.config.Storage.Delete(cacheKey(req))
+
+	for _, name := range []string{"Location", "Content-Location"} {
+		value := resp.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		target, err := req.URL.Parse(value)
+		if err != nil || target.Host != req.URL.Host || target.Scheme != req.URL.Scheme {
+			continue
+		}
+		t.config.Storage.Delete(urlCacheKey(target))
+	}
+}
+
+// response builds the response served from entry, with its Age header.
+func (t *cacheTransport) response(entry *cacheEntry, req *http.Request) *http.Response {
+	resp := entry.response(req)
+	resp.Header.Set("Age", strconv.FormatInt(int64(entry.currentAge(t.now())/time.Second), 10))
+	return resp
+}
+
+// gatewayTimeout builds the 504 response to an only-if-cached request that
+// cannot be served from the cache.
+func (t *cacheTransport) gatewayTimeout(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "504 " + http.StatusText(http.StatusGatewayTimeout),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
+
+// freshnessLifetime returns how long the entry is fresh after it was
+// generated: max-age, then Expires minus Date, then a heuristic of 10% of the
+// time since Last-Modified, capped at 24 hours (RFC 9111, Section 4.2.1).
+func (e *cacheEntry) freshnessLifetime(cc cacheControl) time.Duration {
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+
+	date := e.date()
+	if expires := e.Header.Get("Expires"); expires != "" {
+		// Invalid dates, such as "0", mean already expired
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		return t.Sub(date)
+	}
+
+	if !heuristicallyCacheable[e.StatusCode] {
+		return 0
+	}
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && lastModified.Before(date) {
+		return min(date.Sub(lastModified)/heuristicFraction, maxHeuristicLifetime)
+	}
+	return 0
+}
+
+// currentAge returns the age of the entry at now (RFC 9111, Section 4.2.3).
+func (e *cacheEntry) currentAge(now time.Time) time.Duration {
+	apparentAge := max(0, e.ResponseTime.Sub(e.date()))
+
+	var ageValue time.Duration
+	if age, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && age > 0 {
+		ageValue = time.Duration(age) * time.Second
+	}
+	correctedAgeValue := ageValue + e.ResponseTime.Sub(e.RequestTime)
+
+	return max(apparentAge, correctedAgeValue) + now.Sub(e.ResponseTime)
+}
+
+// date returns the Date of the stored response, or the time it was received.
+func (e *cacheEntry) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+	return e.ResponseTime
+}
+
+// response builds a response to req from the stored status, headers and body.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// update refreshes the entry with the headers of a 304 response to a
+// revalidation (RFC 9111, Section 4.3.4).
+func (e *cacheEntry) update(header http.Header, requestTime, responseTime time.Time) {
+	for name, values := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Content-Range":
+			continue
+		}
+		e.Header[name] = append([]string(nil), values...)
+	}
+	e.RequestTime = requestTime
+	e.ResponseTime = responseTime
+}
+
+// conditionalRequest returns a copy of req carrying If-None-Match and
+// If-Modified-Since from the validators in header, or nil if there are none.
+func conditionalRequest(req *http.Request, header http.Header) *http.Request {
+	if !hasValidators(header) {
+		return nil
+	}
+
+	conditional := req.Clone(req.Context())
+	if etag := header.Get("ETag"); etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+	return conditional
+}
+
+// hasValidators reports whether header carries an ETag or a Last-Modified date.
+func hasValidators(header http.Header) bool {
+	return header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+}
+
+// varyValues returns the request header values of the fields listed in the
+// Vary header of a response, or nil.
+func varyValues(req *http.Request, header http.Header) map[string]string {
+	var values map[string]string
+	for _, vary := range header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[name] = headerValue(req.Header, name)
+		}
+	}
+	return values
+}
+
+// headerValue returns all values of the header field name joined by commas.
+func headerValue(header http.Header, name string) string {
+	return strings.Join(header.Values(name), ",")
+}
+
+// cacheKey returns the storage key of the responses to req.
+func cacheKey(req *http.Request) string {
+	return urlCacheKey(req.URL)
+}
+
+// urlCacheKey returns the storage key of the responses for u, without fragment.
+func urlCacheKey(u *url.URL) string {
+	key := *u
+	key.Fragment = ""
+	key.RawFragment = ""
+	return key.String()
+}
+
+// isSafeMethod reports whether method is safe (RFC 9110, Section 9.2.1).
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// LRUCache is an in-memory CacheStorage that evicts the least recently used
+// entry once it holds its maximum number of entries. It is safe for
+// concurrent use.
+//
+// Example:
+//
+//	storage := httpc.NewLRUCache(500)
+//	client := httpc.NewClient(httpc.WithCache(httpc.CacheConfig{Storage: storage}))
+type LRUCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// lruItem is an element of the LRUCache recency list.
+type lruItem struct {
+	key   string
+	value []byte
+}
+
+// NewLRUCache creates an LRUCache holding up to maxEntries entries. If
+// maxEntries is not positive, 1000 is used.
+func NewLRUCache(maxEntries int) *LRUCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheEntries
+	}
+	return &LRUCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStorage, marking the entry as recently used.
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruItem).value, true
+}
+
+// Set implements CacheStorage, evicting the least recently used entry if the
+// cache is full.
+func (c *LRUCache) Set(key string, entry []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruItem).value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruItem{key: key, value: entry})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Delete implements CacheStorage.
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of stored entries.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,317 @@
+// Package httpc provides tests for HTTP caching.
+// This file contains tests for the RFC 9111 cache transport and the LRU storage.
+package httpc
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestCacheClient creates a client with a cache transport whose clock is
+// read from *now.
+func newTestCacheClient(now *time.Time, config CacheConfig) *Client {
+	return NewClient(WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		transport := newCacheTransport(rt, config)
+		transport.now = func() time.Time { return *now }
+		return transport
+	}))
+}
+
+// getBody sends a GET request and reads the response body.
+func getBody(t *testing.T, client *Client, url string, headers ...string) (*Response, string) {
+	t.Helper()
+
+	rb := client.NewRequest().Method(http.MethodGet).URL(url)
+	for i := 0; i+1 < len(headers); i += 2 {
+		rb.Header(headers[i], headers[i+1])
+	}
+	resp, err := rb.Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("String() failed: %v", err)
+	}
+	return resp, body
+}
+
+func TestClient_WithCache_MaxAge(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = fmt.Fprintf(w, "response %d", n)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestCacheClient(&now, CacheConfig{})
+
+	resp, body := getBody(t, client, server.URL)
+	if resp.FromCache() || body != "response 1" {
+		t.Fatalf("Expected first response from the server, got %q (from cache %v)", body, resp.FromCache())
+	}
+
+	now = now.Add(30 * time.Second)
+	resp, body = getBody(t, client, server.URL)
+	if !resp.FromCache() || body != "response 1" {
+		t.Errorf("Expected fresh response from the cache, got %q (from cache %v)", body, resp.FromCache())
+	}
+	if age, _ := strconv.Atoi(resp.Header.Get("Age")); age < 30 {
+		t.Errorf("Expected Age of at least 30s, got %q", resp.Header.Get("Age"))
+	}
+
+	// Once stale, without validators, the response is fetched again
+	now = now.Add(31 * time.Second)
+	resp, body = getBody(t, client, server.URL)
+	if resp.FromCache() || body != "response 2" {
+		t.Errorf("Expected stale response to be refetched, got %q (from cache %v)", body, resp.FromCache())
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 server requests, got %d", requests.Load())
+	}
+}
+
+func TestClient_WithCache_Revalidation(t *testing.T) {
+	var requests, conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// Without Date, ages are measured from the test clock
+		w.Header()["Date"] = nil
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestCacheClient(&now, CacheConfig{})
+
+	getBody(t, client, server.URL)
+
+	now = now.Add(time.Minute)
+	resp, body := getBody(t, client, server.URL)
+	if conditional.Load() != 1 {
+		t.Fatalf("Expected a conditional request, got %d", conditional.Load())
+	}
+	if resp.StatusCode != http.StatusOK || body != "payload" || !resp.FromCache() {
+		t.Errorf("Expected 304 to be served as the stored 200, got %d %q", resp.StatusCode, body)
+	}
+
+	// The revalidation refreshed the stored response
+	now = now.Add(5 * time.Second)
+	if resp, _ := getBody(t, client, server.URL); !resp.FromCache() || requests.Load() != 2 {
+		t.Errorf("Expected refreshed response to be fresh, got %d requests", requests.Load())
+	}
+}
+
+func TestClient_WithCache_Directives(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		reqHeaders   []string
+		wantRequests int32
+	}{
+		{name: "no-store", cacheControl: "no-store, max-age=60", wantRequests: 2},
+		{name: "response no-cache", cacheControl: "no-cache, max-age=60", wantRequests: 2},
+		{name: "request no-cache", cacheControl: "max-age=60", reqHeaders: []string{"Cache-Control", "no-cache"}, wantRequests: 2},
+		{name: "request max-age", cacheControl: "max-age=60", reqHeaders: []string{"Cache-Control", "max-age=5"}, wantRequests: 2},
+		{name: "request max-stale", cacheControl: "max-age=1", reqHeaders: []string{"Cache-Control", "max-stale=60"}, wantRequests: 1},
+		{name: "must-revalidate", cacheControl: "max-age=1, must-revalidate", reqHeaders: []string{"Cache-Control", "max-stale"}, wantRequests: 2},
+		{name: "expires", cacheControl: "", wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				} else {
+					w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+				}
+				_, _ = w.Write([]byte("payload"))
+			}))
+			defer server.Close()
+
+			now := time.Now()
+			client := newTestCacheClient(&now, CacheConfig{})
+
+			getBody(t, client, server.URL)
+			now = now.Add(10 * time.Second)
+			getBody(t, client, server.URL, tt.reqHeaders...)
+
+			if requests.Load() != tt.wantRequests {
+				t.Errorf("Expected %d server requests, got %d", tt.wantRequests, requests.Load())
+			}
+		})
+	}
+}
+
+func TestClient_WithCache_Heuristic(t *testing.T) {
+	var requests atomic.Int32
+	lastModified := time.Now().Add(-30 * 24 * time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestCacheClient(&now, CacheConfig{})
+	getBody(t, client, server.URL)
+
+	// 10% of 30 days is capped at 24 hours
+	now = now.Add(23 * time.Hour)
+	if resp, _ := getBody(t, client, server.URL); !resp.FromCache() {
+		t.Error("Expected heuristically fresh response from the cache")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if resp, _ := getBody(t, client, server.URL); resp.FromCache() {
+		t.Error("Expected heuristic freshness to be capped at 24 hours")
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 server requests, got %d", requests.Load())
+	}
+}
+
+func TestClient_WithCache_Vary(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte("hello in " + r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestCacheClient(&now, CacheConfig{})
+
+	getBody(t, client, server.URL, "Accept-Language", "en")
+	if resp, body := getBody(t, client, server.URL, "Accept-Language", "en"); !resp.FromCache() || body != "hello in en" {
+		t.Errorf("Expected matching variant from the cache, got %q", body)
+	}
+	if resp, body := getBody(t, client, server.URL, "Accept-Language", "fr"); resp.FromCache() || body != "hello in fr" {
+		t.Errorf("Expected a different variant to be fetched, got %q", body)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 server requests, got %d", requests.Load())
+	}
+}
+
+func TestClient_WithCache_Invalidation(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests.Add(1)
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestCacheClient(&now, CacheConfig{})
+
+	getBody(t, client, server.URL+"/users")
+	if _, err := client.Post(server.URL+"/users", map[string]string{"name": "john"}); err != nil {
+		t.Fatalf("Post() failed: %v", err)
+	}
+	if resp, _ := getBody(t, client, server.URL+"/users"); resp.FromCache() {
+		t.Error("Expected POST to invalidate the stored response")
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 server GET requests, got %d", requests.Load())
+	}
+}
+
+func TestClient_WithCache_OnlyIfCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request to reach the server")
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestCacheClient(&now, CacheConfig{})
+
+	resp, _ := getBody(t, client, server.URL, "Cache-Control", "only-if-cached")
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 for an uncached only-if-cached request, got %d", resp.StatusCode)
+	}
+}
+
+func TestClient_WithCache_PartialBodyNotStored(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := newTestCacheClient(&now, CacheConfig{MaxBodySize: 5})
+
+	getBody(t, client, server.URL)
+	if resp, body := getBody(t, client, server.URL); resp.FromCache() || body != "0123456789" {
+		t.Errorf("Expected body larger than MaxBodySize not to be stored, got %q", body)
+	}
+
+	// A body closed before it is fully read is not stored either
+	client = newTestCacheClient(&now, CacheConfig{})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp, _ := getBody(t, client, server.URL); resp.FromCache() {
+		t.Error("Expected unread body not to be stored")
+	}
+	if requests.Load() != 4 {
+		t.Errorf("Expected 4 server requests, got %d", requests.Load())
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2)
+
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+	cache.Get("a") // b is now the least recently used
+	cache.Set("c", []byte("3"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if value, ok := cache.Get("a"); !ok || string(value) != "1" {
+		t.Errorf("Expected a to be kept, got %q", value)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+
+	cache.Set("a", []byte("updated"))
+	if value, _ := cache.Get("a"); string(value) != "updated" {
+		t.Errorf("Expected a to be replaced, got %q", value)
+	}
+
+	cache.Delete("a")
+	if _, ok := cache.Get("a"); ok || cache.Len() != 1 {
+		t.Error("Expected a to be deleted")
+	}
+}
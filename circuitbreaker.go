@@ -0,0 +1,338 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the per-host circuit breaker transport.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors.Is for every *CircuitOpenError, that is,
+// for requests rejected without being sent because the circuit of their host is open.
+//
+// Example:
+//
+//	_, err := client.Get("/api/users")
+//	if errors.Is(err, httpc.ErrCircuitOpen) {
+//		return cachedUsers()
+//	}
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned when a request is rejected by an open circuit.
+//
+// Example:
+//
+//	var openErr *httpc.CircuitOpenError
+//	if errors.As(err, &openErr) {
+//		log.Printf("%s unavailable, retry in %v", openErr.Host, openErr.RetryAfter)
+//	}
+type CircuitOpenError struct {
+	// Host is the host whose circuit is open
+	Host string
+
+	// RetryAfter is the time left until the circuit lets a trial request through
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for %s (retry in %v)", e.Host, e.RetryAfter)
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+// Circuit breaker states.
+const (
+	// CircuitClosed lets all requests through while counting failures.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects all requests with ErrCircuitOpen.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a limited number of trial requests through to
+	// decide whether to close or reopen the circuit.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreakerConfig configures a circuit breaker. Each host has its own
+// circuit, which opens when the failure rate over Window reaches
+// FailureThreshold, rejects requests for OpenTimeout, and then lets
+// HalfOpenRequests trial requests through: if they all succeed the circuit
+// closes, otherwise it opens again.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the failure rate (0.5 = 50%) that opens the circuit.
+	// If zero, 0.5 is used.
+	FailureThreshold float64
+
+	// MinRequests is the number of requests that must be seen within Window
+	// before the failure rate is considered. If zero, 10 is used.
+	MinRequests int
+
+	// Window is the period over which requests and failures are counted.
+	// If zero, 10 seconds is used.
+	Window time.Duration
+
+	// OpenTimeout is how long the circuit stays open before trial requests are
+	// allowed. If zero, 30 seconds is used.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of trial requests let through, and required
+	// to succeed, while half-open. If zero, 1 is used.
+	HalfOpenRequests int
+
+	// FailIf decides whether a response or error counts as a failure. It has the
+	// same signature as RetryConfig.RetryIf, so the same predicate can be shared.
+	// If nil, the default retry condition is used: errors, 5xx and 429 responses.
+	// Requests cancelled by their own context never count as failures, but
+	// requests that exceed a deadline or timeout do.
+	FailIf func(*http.Response, error) bool
+
+	// OnStateChange, if set, is called whenever the circuit of a host changes state.
+	// It is called synchronously on the goroutine of the request causing the change.
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// Defaults used when CircuitBreakerConfig fields are not set.
+const (
+	defaultCircuitFailureThreshold = 0.5
+	defaultCircuitMinRequests      = 10
+	defaultCircuitWindow           = 10 * time.Second
+	defaultCircuitOpenTimeout      = 30 * time.Second
+)
+
+// circuitBucket holds the request and failure counts for one second of the window.
+type circuitBucket struct {
+	second   int64
+	requests int
+	failures int
+}
+
+// circuit is the breaker state of a single host. Its fields are guarded by
+// circuitBreakerTransport.mu.
+type circuit struct {
+	state     CircuitState
+	buckets   []circuitBucket
+	openedAt  time.Time
+	trials    int
+	successes int
+}
+
+// circuitBreakerTransport is an http.RoundTripper that keeps a circuit per host
+// and rejects requests to hosts whose circuit is open.
+type circuitBreakerTransport struct {
+	transport http.RoundTripper
+	config    CircuitBreakerConfig
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+	now      func() time.Time
+}
+
+// newCircuitBreakerTransport creates a circuitBreakerTransport.
+func newCircuitBreakerTransport(rt http.RoundTripper, config CircuitBreakerConfig) *circuitBreakerTransport {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultCircuitFailureThreshold
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = defaultCircuitMinRequests
+	}
+	if config.Window <= 0 {
+		config.Window = defaultCircuitWindow
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaultCircuitOpenTimeout
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.FailIf == nil {
+		config.FailIf = defaultRetryCondition
+	}
+
+	return &circuitBreakerTransport{
+		transport: rt,
+		config:    config,
+		circuits:  make(map[string]*circuit),
+		now:       time.Now,
+	}
+}
+
+// RoundTrip implements http.RoundTripper by rejecting the request when the
+// circuit of its host is open and recording the outcome otherwise.
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := t.allow(host); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+
+	// The caller gave up, which says nothing about the health of the host.
+	// Deadlines (attempt, request or client timeouts) do count: a host that
+	// hangs is exactly what the breaker must catch.
+	if errors.Is(req.Context().Err(), context.Canceled) {
+		t.cancel(host)
+		return resp, err
+	}
+
+	t.record(host, t.config.FailIf(resp, err))
+	return resp, err
+}
+
+// circuitFor returns the circuit of host, creating it on first use.
+// The caller must hold t.mu.
+func (t *circuitBreakerTransport) circuitFor(host string) *circuit {
+	c, ok := t.circuits[host]
+	if !ok {
+		seconds := int((t.config.Window + time.Second - 1) / time.Second)
+		c = &circuit{buckets: make([]circuitBucket, seconds)}
+		t.circuits[host] = c
+	}
+	return c
+}
+
+// stateChange is a state transition of the circuit of a host, reported to
+// CircuitBreakerConfig.OnStateChange once t.mu has been released.
+type stateChange struct {
+	host     string
+	from, to CircuitState
+}
+
+// setState moves c to state and returns the transition to report.
+// The caller must hold t.mu.
+func (t *circuitBreakerTransport) setState(host string, c *circuit, state CircuitState) *stateChange {
+	from := c.state
+	c.state = state
+	c.trials = 0
+	c.successes = 0
+
+	switch state {
+	case CircuitOpen:
+		c.openedAt = t.now()
+	case CircuitClosed:
+		for i := range c.buckets {
+			c.buckets[i] = circuitBucket{}
+		}
+	}
+
+	return &stateChange{host: host, from: from, to: state}
+}
+
+// notify reports change, if any, to OnStateChange. It must be called without
+// holding t.mu, so the callback may safely use the Client.
+func (t *circuitBreakerTransport) notify(change *stateChange) {
+	if change != nil && t.config.OnStateChange != nil {
+		t.config.OnStateChange(change.host, change.from, change.to)
+	}
+}
+
+// allow reports whether a request to host may be sent, returning a
+// *CircuitOpenError if not.
+func (t *circuitBreakerTransport) allow(host string) error {
+	var change *stateChange
+	defer func() { t.notify(change) }()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.circuitFor(host)
+
+	if c.state == CircuitOpen {
+		remaining := t.config.OpenTimeout - t.now().Sub(c.openedAt)
+		if remaining > 0 {
+			return &CircuitOpenError{Host: host, RetryAfter: remaining}
+		}
+		change = t.setState(host, c, CircuitHalfOpen)
+	}
+
+	if c.state == CircuitHalfOpen {
+		if c.trials >= t.config.HalfOpenRequests {
+			return &CircuitOpenError{Host: host}
+		}
+		c.trials++
+	}
+
+	return nil
+}
+
+// cancel gives back a trial slot taken by a request that did not complete.
+func (t *circuitBreakerTransport) cancel(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c := t.circuitFor(host); c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+// record counts the outcome of a request to host and updates its state.
+func (t *circuitBreakerTransport) record(host string, failed bool) {
+	var change *stateChange
+	defer func() { t.notify(change) }()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.circuitFor(host)
+
+	switch c.state {
+	case CircuitHalfOpen:
+		if failed {
+			change = t.setState(host, c, CircuitOpen)
+			return
+		}
+		c.successes++
+		if c.successes >= t.config.HalfOpenRequests {
+			change = t.setState(host, c, CircuitClosed)
+		}
+
+	case CircuitClosed:
+		second := t.now().Unix()
+		bucket := &c.buckets[int(second%int64(len(c.buckets)))]
+		if bucket.second != second {
+			*bucket = circuitBucket{second: second}
+		}
+		bucket.requests++
+		if failed {
+			bucket.failures++
+		}
+
+		oldest := second - int64(len(c.buckets)) + 1
+		var requests, failures int
+		for _, b := range c.buckets {
+			if b.second >= oldest {
+				requests += b.requests
+				failures += b.failures
+			}
+		}
+
+		if requests >= t.config.MinRequests &&
+			float64(failures) >= t.config.FailureThreshold*float64(requests) {
+			change = t.setState(host, c, CircuitOpen)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package httpc provides tests for the circuit breaker.
+// This file contains tests for tripping, half-open recovery, per-host circuits,
+// failure classification, and state-change callbacks.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WithCircuitBreaker_Opens(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithCircuitBreaker(CircuitBreakerConfig{
+		MinRequests: 3,
+		OpenTimeout: time.Minute,
+	}))
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		_, _ = resp.Bytes()
+	}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("Expected *CircuitOpenError, got %T", err)
+	}
+	if openErr.RetryAfter <= 0 || openErr.RetryAfter > time.Minute {
+		t.Errorf("Expected RetryAfter within the open timeout, got %v", openErr.RetryAfter)
+	}
+
+	if calls.Load() != 3 {
+		t.Errorf("Expected the open circuit to stop requests at 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestCircuitBreakerTransport_HalfOpen(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	var states []CircuitState
+	transport := newCircuitBreakerTransport(defaultTransport(), CircuitBreakerConfig{
+		MinRequests:      2,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 2,
+		OnStateChange: func(host string, from, to CircuitState) {
+			if host != "api.example.com" {
+				t.Errorf("Unexpected host %q", host)
+			}
+			states = append(states, to)
+		},
+	})
+	transport.now = func() time.Time { return now }
+
+	const host = "api.example.com"
+
+	for i := 0; i < 2; i++ {
+		if err := transport.allow(host); err != nil {
+			t.Fatalf("allow() failed: %v", err)
+		}
+		transport.record(host, true)
+	}
+
+	if err := transport.allow(host); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected circuit to be open, got %v", err)
+	}
+
+	// After the open timeout, only HalfOpenRequests trials are let through
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 2; i++ {
+		if err := transport.allow(host); err != nil {
+			t.Fatalf("Trial %d rejected: %v", i, err)
+		}
+	}
+	if err := transport.allow(host); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected extra trial to be rejected, got %v", err)
+	}
+
+	transport.record(host, false)
+	transport.record(host, false)
+
+	if err := transport.allow(host); err != nil {
+		t.Errorf("Expected circuit to be closed after successful trials, got %v", err)
+	}
+
+	expected := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(states) != len(expected) {
+		t.Fatalf("Expected transitions %v, got %v", expected, states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Fatalf("Expected transitions %v, got %v", expected, states)
+		}
+	}
+}
+
+func TestCircuitBreakerTransport_HalfOpenFailureReopens(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	transport := newCircuitBreakerTransport(defaultTransport(), CircuitBreakerConfig{
+		MinRequests: 1,
+		OpenTimeout: 10 * time.Second,
+	})
+	transport.now = func() time.Time { return now }
+
+	_ = transport.allow("a")
+	transport.record("a", true)
+
+	now = now.Add(10 * time.Second)
+	if err := transport.allow("a"); err != nil {
+		t.Fatalf("Expected trial request, got %v", err)
+	}
+	transport.record("a", true)
+
+	var openErr *CircuitOpenError
+	if err := transport.allow("a"); !errors.As(err, &openErr) {
+		t.Fatalf("Expected circuit to reopen, got %v", err)
+	}
+	if openErr.RetryAfter != 10*time.Second {
+		t.Errorf("Expected a fresh open timeout, got %v", openErr.RetryAfter)
+	}
+}
+
+func TestCircuitBreakerTransport_FailureThreshold(t *testing.T) {
+	transport := newCircuitBreakerTransport(defaultTransport(), CircuitBreakerConfig{
+		FailureThreshold: 0.5,
+		MinRequests:      4,
+	})
+
+	// 1 failure out of 4 stays below the threshold
+	for _, failed := range []bool{true, false, false, false} {
+		_ = transport.allow("a")
+		transport.record("a", failed)
+	}
+	if err := transport.allow("a"); err != nil {
+		t.Fatalf("Expected circuit to stay closed, got %v", err)
+	}
+
+	// Two more failures bring the rate to 3 out of 6
+	transport.record("a", true)
+	transport.record("a", true)
+	if err := transport.allow("a"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected circuit to open at 50%% failures, got %v", err)
+	}
+
+	// Other hosts have their own circuit
+	if err := transport.allow("b"); err != nil {
+		t.Errorf("Expected host b to be unaffected, got %v", err)
+	}
+}
+
+func TestCircuitBreakerTransport_FailIf(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// Only errors count, so 429 responses never open the circuit
+	client := NewClient(WithCircuitBreaker(CircuitBreakerConfig{
+		MinRequests: 1,
+		FailIf: func(resp *http.Response, err error) bool {
+			return err != nil
+		},
+	}))
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		_, _ = resp.Bytes()
+	}
+}
+
+func TestCircuitBreakerTransport_IgnoresCancellation(t *testing.T) {
+	var hang atomic.Bool
+	hang.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hang.Load() {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithCircuitBreaker(CircuitBreakerConfig{MinRequests: 1}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if _, err := client.Get(server.URL, WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the request to be cancelled, got %v", err)
+	}
+
+	hang.Store(false)
+	if _, err := client.Get(server.URL); err != nil {
+		t.Errorf("Expected cancelled request not to open the circuit, got %v", err)
+	}
+}
+
+func TestClient_WithCircuitBreaker_AttemptTimeoutsOpen(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithCircuitBreaker(CircuitBreakerConfig{MinRequests: 3, OpenTimeout: time.Minute}),
+		WithRetry(RetryConfig{
+			MaxRetries:     5,
+			Backoff:        time.Millisecond,
+			AttemptTimeout: 20 * time.Millisecond,
+			RetryIf:        defaultRetryCondition,
+		}),
+	)
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected timed out attempts to open the circuit, got %v", err)
+	}
+
+	// The circuit opened after MinRequests timeouts, before retries ran out
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected 3 calls before the circuit opened, got %d", got)
+	}
+}
+
+func TestClient_WithCircuitBreaker_NotRetried(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := DefaultRetryConfig()
+	config.MaxRetries = 5
+	config.Backoff = time.Millisecond
+
+	client := NewClient(
+		WithCircuitBreaker(CircuitBreakerConfig{MinRequests: 2, OpenTimeout: time.Minute}),
+		WithRetry(*config),
+	)
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen once the circuit opens, got %v", err)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected retries to stop when the circuit opened, got %d calls", calls.Load())
+	}
+}
+
+func TestCircuitState_String(t *testing.T) {
+	tests := map[CircuitState]string{
+		CircuitClosed:   "closed",
+		CircuitOpen:     "open",
+		CircuitHalfOpen: "half-open",
+		CircuitState(7): "CircuitState(7)",
+	}
+
+	for state, expected := range tests {
+		if got := state.String(); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+}
@@ -16,6 +16,11 @@ type Client struct {
 	headers    map[string]string
 	httpClient *http.Client
 	transport  http.RoundTripper
+	redaction  *RedactionPolicy
+	hooks      *hooks
+	requestID  *requestIDConfig
+	retry      bool
+	tracing    bool
 	mu         *sync.RWMutex
 }
 
@@ -35,11 +40,28 @@ type Client struct {
 //   - WithAuthorization: Add Bearer token authentication
 //   - WithBaseAuth: Add HTTP Basic authentication
 //   - WithApiKey: Add API key authentication
-//   - WithRequestId: Add unique request ID header
+//   - WithRequestId: Add a unique request ID header to each request
+//   - WithRequestIdGenerator: Add request IDs from a custom generator
 //   - WithRetry: Configure automatic retry logic
+//   - WithHedging: Send hedged copies of slow requests
+//   - WithRateLimit: Limit the request rate with a token bucket
+//   - WithKeyedRateLimit: Limit the request rate per host, route or header
+//   - WithAdaptiveRateLimit: Pace requests by server-advertised rate limits
+//   - WithMaxConcurrency: Cap in-flight requests with a priority queue
+//   - WithCircuitBreaker: Stop calling failing hosts with a per-host circuit breaker
+//   - WithMetrics: Record request metrics to a MetricsCollector
+//   - WithTimings: Expose per-phase request timings on Response
+//   - WithTracing: Propagate W3C Trace Context and record client spans
+//   - WithCache: Cache responses according to RFC 9111
+//   - WithRevalidation: Revalidate repeated GET requests with ETag and Last-Modified
 //   - WithLogger: Add request/response logging
+//   - WithSlog: Add structured request logging with log/slog
+//   - WithRedaction: Mask secrets in logged headers, URLs and bodies
+//   - WithHAR: Record exchanges into a HAR file
+//   - WithCurlOnError: Log failed requests as curl commands
 //   - WithDebug: Enable debug mode
 //   - WithBlockedList: Block requests to specific domains
+//   - WithOnRequest, WithOnResponse, WithOnError, WithOnRetry: Register lifecycle hooks
 //   - WithInterceptor: Add custom request/response interceptor
 //
 // Example:
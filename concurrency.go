@@ -0,0 +1,251 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the concurrency limiting transport with a priority queue.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ErrQueueFull is returned when a request cannot be queued because the
+// concurrency limiter queue already holds ConcurrencyConfig.MaxQueue requests.
+var ErrQueueFull = errors.New("request queue is full")
+
+// Priority orders requests waiting for a concurrency slot. Requests with a
+// higher priority are started first; requests with equal priority are started
+// in arrival order. The zero value is PriorityNormal.
+type Priority int
+
+// Request priorities used by the concurrency limiter.
+const (
+	// PriorityLow is for background and batch requests.
+	PriorityLow Priority = -10
+
+	// PriorityNormal is the default priority.
+	PriorityNormal Priority = 0
+
+	// PriorityHigh is for interactive requests that should overtake others.
+	PriorityHigh Priority = 10
+)
+
+// priorityKey is the context key under which the request priority is stored.
+type priorityKey struct{}
+
+// withPriority returns a copy of ctx carrying the request priority.
+func withPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// priorityFrom returns the request priority stored in ctx, or PriorityNormal.
+func priorityFrom(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+// ConcurrencyConfig configures the concurrency limiter.
+type ConcurrencyConfig struct {
+	// MaxInFlight is the maximum number of requests in flight across the Client.
+	// A request stays in flight until its response body is closed or fully read.
+	MaxInFlight int
+
+	// MaxInFlightPerHost additionally caps in-flight requests per host.
+	// If zero, there is no per-host cap.
+	MaxInFlightPerHost int
+
+	// MaxQueue is the maximum number of requests waiting for a slot. Requests
+	// arriving when the queue is full fail with ErrQueueFull. If zero, the queue
+	// is unbounded.
+	MaxQueue int
+}
+
+// concurrencyWaiter is a request waiting for a slot.
+type concurrencyWaiter struct {
+	priority Priority
+	seq      uint64
+	host     string
+	ready    chan struct{}
+	granted  bool
+}
+
+// concurrencyTransport is an http.RoundTripper that caps in-flight requests
+// and queues the excess by priority.
+type concurrencyTransport struct {
+	transport http.RoundTripper
+	config    ConcurrencyConfig
+
+	mu       sync.Mutex
+	inFlight int
+	perHost  map[string]int
+	waiters  []*concurrencyWaiter
+	seq      uint64
+}
+
+// newConcurrencyTransport creates a concurrencyTransport.
+func newConcurrencyTransport(rt http.RoundTripper, config ConcurrencyConfig) *concurrencyTransport {
+	return &concurrencyTransport{
+		transport: rt,
+		config:    config,
+		perHost:   make(map[string]int),
+	}
+}
+
+// RoundTrip implements http.RoundTripper by waiting for a slot, sending the
+// request, and releasing the slot once the response body is done.
+func (t *concurrencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := t.acquire(req.Context(), host, priorityFrom(req.Context())); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		t.release(host)
+		return resp, err
+	}
+
+	resp.Body = &releaseOnCloseBody{ReadCloser: resp.Body, release: func() { t.release(host) }}
+	return resp, nil
+}
+
+// canRun reports whether a request for host fits within the limits.
+// The caller must hold t.mu.
+func (t *concurrencyTransport) canRun(host string) bool {
+	if t.config.MaxInFlight > 0 && t.inFlight >= t.config.MaxInFlight {
+		return false
+	}
+	if t.config.MaxInFlightPerHost > 0 && t.perHost[host] >= t.config.MaxInFlightPerHost {
+		return false
+	}
+	return true
+}
+
+// start marks a request for host as in flight. The caller must hold t.mu.
+func (t *concurrencyTransport) start(host string) {
+	t.inFlight++
+	t.perHost[host]++
+}
+
+// acquire waits for a slot for host, honoring priority and ctx.
+func (t *concurrencyTransport) acquire(ctx context.Context, host string, priority Priority) error {
+	t.mu.Lock()
+
+	// Queued requests are never runnable (see dispatch), so a free slot can be
+	// taken right away without overtaking anyone.
+	if t.canRun(host) {
+		t.start(host)
+		t.mu.Unlock()
+		return nil
+	}
+
+	if t.config.MaxQueue > 0 && len(t.waiters) >= t.config.MaxQueue {
+		t.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	t.seq++
+	w := &concurrencyWaiter{priority: priority, seq: t.seq, host: host, ready: make(chan struct{})}
+	t.enqueue(w)
+	t.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if w.granted {
+			// The slot was granted while we were giving up, so hand it on
+			t.finish(host)
+		} else {
+			t.remove(w)
+		}
+		return ctx.Err()
+	}
+}
+
+// enqueue inserts w keeping waiters sorted by priority, then arrival order.
+// The caller must hold t.mu.
+func (t *concurrencyTransport) enqueue(w *concurrencyWaiter) {
+	i := sort.Search(len(t.waiters), func(i int) bool {
+		other := t.waiters[i]
+		return other.priority < w.priority || (other.priority == w.priority && other.seq > w.seq)
+	})
+	t.waiters = append(t.waiters, nil)
+	copy(t.waiters[i+1:], t.waiters[i:])
+	t.waiters[i] = w
+}
+
+// remove drops w from the queue. The caller must hold t.mu.
+func (t *concurrencyTransport) remove(w *concurrencyWaiter) {
+	for i, other := range t.waiters {
+		if other == w {
+			t.waiters = append(t.waiters[:i], t.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch starts queued requests in priority order while slots are available.
+// Requests for a saturated host are skipped so they do not block other hosts.
+// The caller must hold t.mu.
+func (t *concurrencyTransport) dispatch() {
+	for i := 0; i < len(t.waiters); {
+		w := t.waiters[i]
+		if !t.canRun(w.host) {
+			if t.config.MaxInFlight > 0 && t.inFlight >= t.config.MaxInFlight {
+				return
+			}
+			i++
+			continue
+		}
+
+		t.start(w.host)
+		w.granted = true
+		close(w.ready)
+		t.waiters = append(t.waiters[:i], t.waiters[i+1:]...)
+	}
+}
+
+// finish releases the slot held by a request for host. The caller must hold t.mu.
+func (t *concurrencyTransport) finish(host string) {
+	t.inFlight--
+	if t.perHost[host]--; t.perHost[host] <= 0 {
+		delete(t.perHost, host)
+	}
+	t.dispatch()
+}
+
+// release releases the slot held by a request for host.
+func (t *concurrencyTransport) release(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.finish(host)
+}
+
+// releaseOnCloseBody calls release once, when the body is closed or fully read.
+type releaseOnCloseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Read reads from the underlying body and releases the slot at EOF.
+func (b *releaseOnCloseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+// Close closes the underlying body and releases the slot.
+func (b *releaseOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,213 @@
+// Package httpc provides tests for the concurrency limiter.
+// This file contains tests for in-flight caps, per-host caps, bounded queues,
+// priorities, and context cancellation while queued.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WithMaxConcurrency_CapsInFlight(t *testing.T) {
+	var current, peak atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		current.Add(-1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithMaxConcurrency(ConcurrencyConfig{MaxInFlight: 2}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Errorf("Get() failed: %v", err)
+				return
+			}
+			_, _ = resp.Bytes()
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 requests in flight, saw %d", peak.Load())
+	}
+}
+
+func TestConcurrencyTransport_PerHost(t *testing.T) {
+	transport := newConcurrencyTransport(defaultTransport(), ConcurrencyConfig{MaxInFlightPerHost: 1})
+
+	if err := transport.acquire(context.Background(), "a", PriorityNormal); err != nil {
+		t.Fatalf("acquire(a) failed: %v", err)
+	}
+
+	// Another host is not affected by the saturated one
+	if err := transport.acquire(context.Background(), "b", PriorityNormal); err != nil {
+		t.Fatalf("acquire(b) failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := transport.acquire(ctx, "a", PriorityNormal); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected second request for host a to wait, got %v", err)
+	}
+}
+
+func TestConcurrencyTransport_QueueFull(t *testing.T) {
+	transport := newConcurrencyTransport(defaultTransport(), ConcurrencyConfig{MaxInFlight: 1, MaxQueue: 1})
+
+	if err := transport.acquire(context.Background(), "a", PriorityNormal); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	go func() { _ = transport.acquire(context.Background(), "a", PriorityNormal) }()
+
+	// Wait for the first waiter to be queued
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		transport.mu.Lock()
+		queued := len(transport.waiters)
+		transport.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := transport.acquire(context.Background(), "a", PriorityNormal); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestConcurrencyTransport_Priority(t *testing.T) {
+	transport := newConcurrencyTransport(defaultTransport(), ConcurrencyConfig{MaxInFlight: 1})
+
+	if err := transport.acquire(context.Background(), "a", PriorityNormal); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+
+	for _, priority := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		wg.Add(1)
+		go func(priority Priority) {
+			defer wg.Done()
+			if err := transport.acquire(context.Background(), "a", priority); err != nil {
+				t.Errorf("acquire failed: %v", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+			transport.release("a")
+		}(priority)
+	}
+
+	// Wait until all three are queued before freeing the slot
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		transport.mu.Lock()
+		queued := len(transport.waiters)
+		transport.mu.Unlock()
+		if queued == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	transport.release("a")
+	wg.Wait()
+
+	expected := []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected start order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestConcurrencyTransport_CancelWhileQueued(t *testing.T) {
+	transport := newConcurrencyTransport(defaultTransport(), ConcurrencyConfig{MaxInFlight: 1})
+
+	if err := transport.acquire(context.Background(), "a", PriorityNormal); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	if err := transport.acquire(ctx, "a", PriorityNormal); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	transport.mu.Lock()
+	queued := len(transport.waiters)
+	transport.mu.Unlock()
+	if queued != 0 {
+		t.Errorf("Expected cancelled request to leave the queue, %d still queued", queued)
+	}
+
+	// The slot is handed to the next request once released
+	transport.release("a")
+	if err := transport.acquire(context.Background(), "a", PriorityNormal); err != nil {
+		t.Errorf("acquire after release failed: %v", err)
+	}
+}
+
+func TestClient_WithPriority(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := priorityFrom(r.Context()); got != PriorityNormal {
+			t.Errorf("Priority must not leak to the server context, got %v", got)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var seen Priority
+	client := NewClient(WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			seen = priorityFrom(req.Context())
+			return rt.RoundTrip(req)
+		})
+	}))
+
+	if _, err := client.Get(server.URL, WithPriority(PriorityHigh)); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if seen != PriorityHigh {
+		t.Errorf("Expected PriorityHigh in the request context, got %v", seen)
+	}
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,277 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the export of requests as curl command lines and the
+// transport that logs failed requests as curl commands.
+package httpc
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// curlMaxBodySize bounds the request body captured by WithCurlOnError when the
+// body cannot be replayed through http.Request.GetBody, and the body rendered
+// by RequestBuilder.Curl.
+const curlMaxBodySize = 64 << 10
+
+// curlBodyOmitted is appended, as a shell comment, to commands whose body is
+// larger than curlMaxBodySize.
+const curlBodyOmitted = " # request body omitted, larger than 64 KB"
+
+// Curl returns a curl command line equivalent to the request being built,
+// including the method, URL with query parameters, client and request headers,
+// and body. Headers added by interceptors, such as WithAuthorization, are not
+// part of the builder and are not included; use Response.Curl to export the
+// request as it was sent. Any error recorded while building the request is
+// returned. Bodies larger than 64 KB are omitted rather than read into memory.
+// The body can still be sent with Do afterwards.
+//
+// Example:
+//
+//	cmd, err := client.NewRequest().
+//	    Method("POST").
+//	    URL("/api/users").
+//	    JSON(user).
+//	    Curl()
+//	fmt.Println(cmd)
+//	// curl -X POST 'https://api.example.com/api/users' -H 'Accept: */*' ... --data-raw '{"name":"john"}'
+func (rb *RequestBuilder) Curl() (string, error) {
+	return rb.curl(nil)
+}
+
+// CurlRedacted is like Curl, but masks sensitive headers, query parameters and
+// body fields according to the Client redaction policy (see WithRedaction),
+// so that the command can be shared safely.
+//
+// Example:
+//
+//	cmd, err := client.NewRequest().URL("/api/users?token=secret").CurlRedacted()
+func (rb *RequestBuilder) CurlRedacted() (string, error) {
+	return rb.curl(rb.client.redaction.orDefault())
+}
+
+// curl renders the request being built, masked by policy when it is not nil.
+func (rb *RequestBuilder) curl(policy *RedactionPolicy) (string, error) {
+	if rb.err != nil {
+		return "", rb.err
+	}
+
+	body, omitted, err := rb.curlBody()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(rb.ctx, rb.method, rb.buildURL(), nil)
+	if err != nil {
+		return "", err
+	}
+	rb.applyHeaders(req)
+
+	cmd := curlCommand(req, body, policy)
+	if omitted {
+		cmd += curlBodyOmitted
+	}
+	return cmd, nil
+}
+
+// curlBody returns the builder body for rendering, leaving it available to Do.
+// At most curlMaxBodySize+1 bytes are read; when the body is larger, it is
+// reported as omitted and no bytes are returned.
+func (rb *RequestBuilder) curlBody() ([]byte, bool, error) {
+	if rb.body == nil {
+		return nil, false, nil
+	}
+
+	// In-memory bodies are peeked at without being consumed
+	if buf, ok := rb.body.(*bytes.Buffer); ok {
+		if buf.Len() > curlMaxBodySize {
+			return nil, true, nil
+		}
+		return bytes.Clone(buf.Bytes()), false, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(rb.body, curlMaxBodySize+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if seeker, ok := rb.body.(io.Seeker); ok {
+		if _, err := seeker.Seek(-int64(len(data)), io.SeekCurrent); err != nil {
+			return nil, false, err
+		}
+	} else if len(data) > curlMaxBodySize {
+		rb.body = io.MultiReader(bytes.NewReader(data), rb.body)
+	} else {
+		rb.body = bytes.NewReader(data)
+	}
+
+	if len(data) > curlMaxBodySize {
+		return nil, true, nil
+	}
+	return data, false, nil
+}
+
+// Curl returns a curl command line equivalent to the request that produced
+// the response, as sent by the client: with the headers added by interceptors
+// and the body, when it can be replayed through http.Request.GetBody (as for
+// JSON, XML and other in-memory bodies). It returns an empty string if the
+// request is not available.
+//
+// Example:
+//
+//	resp, err := client.Post("/api/users", user)
+//	if err == nil && resp.StatusCode >= 400 {
+//		log.Printf("failed request: %s", resp.Curl())
+//	}
+func (r *Response) Curl() string {
+	return r.curl(false)
+}
+
+// CurlRedacted is like Curl, but masks sensitive headers, query parameters and
+// body fields according to the Client redaction policy (see WithRedaction).
+func (r *Response) CurlRedacted() string {
+	return r.curl(true)
+}
+
+// curl renders the request of the response, masked when redact is set.
+func (r *Response) curl(redact bool) string {
+	if r.Response == nil || r.Request == nil {
+		return ""
+	}
+
+	var policy *RedactionPolicy
+	if redact {
+		policy = redactionFrom(r.Request.Context(), nil)
+	}
+	return curlCommand(r.Request, replayBody(r.Request), policy)
+}
+
+// replayBody returns a copy of the body of req obtained through GetBody, or
+// nil if the body cannot be replayed.
+func replayBody(req *http.Request) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+
+	body, err := io.ReadAll(rc)
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// curlCommand renders req with body as a curl command line. Headers are
+// sorted for a stable output. When policy is not nil, sensitive values are
+// masked.
+func curlCommand(req *http.Request, body []byte, policy *RedactionPolicy) string {
+	var b strings.Builder
+	b.WriteString("curl")
+
+	switch req.Method {
+	case "", http.MethodGet:
+	case http.MethodHead:
+		// -X HEAD would make curl wait for a response body that never comes
+		b.WriteString(" -I")
+	default:
+		b.WriteString(" -X ")
+		b.WriteString(req.Method)
+	}
+
+	rawURL := req.URL.String()
+	if policy != nil {
+		rawURL = policy.RedactURL(req.URL)
+	}
+	b.WriteString(" ")
+	b.WriteString(shellQuote(rawURL))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if policy != nil {
+				value = policy.RedactHeader(name, value)
+			}
+			b.WriteString(" -H ")
+			b.WriteString(shellQuote(name + ": " + value))
+		}
+	}
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		b.WriteString(" -H ")
+		b.WriteString(shellQuote("Host: " + req.Host))
+	}
+
+	if len(body) > 0 {
+		if policy != nil {
+			body = policy.RedactBody(body, req.Header.Get("Content-Type"))
+		}
+		b.WriteString(" --data-raw ")
+		b.WriteString(shellQuote(string(body)))
+	}
+
+	return b.String()
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// curlOnErrorTransport is an http.RoundTripper that logs the curl command of
+// every failed request.
+type curlOnErrorTransport struct {
+	transport http.RoundTripper
+	logger    *log.Logger
+}
+
+// RoundTrip implements http.RoundTripper by sending the request and, when it
+// fails with an error or a 4xx or 5xx status, logging it as a redacted curl
+// command. Bodies that cannot be replayed through GetBody are captured as they
+// are sent, up to 64 KB.
+func (t *curlOnErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var captured *captureBody
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		captured = &captureBody{ReadCloser: req.Body, limit: curlMaxBodySize}
+		req = req.Clone(req.Context())
+		req.Body = captured
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err == nil && resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+
+	// Prefer the request as sent, which carries headers added by inner transports
+	sent := req
+	if resp != nil && resp.Request != nil {
+		sent = resp.Request
+	}
+
+	var body []byte
+	if captured != nil {
+		body, _, _ = captured.captured()
+	} else {
+		body = replayBody(sent)
+	}
+
+	cmd := curlCommand(sent, body, redactionFrom(req.Context(), nil))
+	if err != nil {
+		t.logger.Printf("request failed: %v: %s", err, cmd)
+	} else {
+		t.logger.Printf("request failed with status %d: %s", resp.StatusCode, cmd)
+	}
+
+	return resp, err
+}
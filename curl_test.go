@@ -0,0 +1,252 @@
+// Package httpc provides tests for curl export.
+// This file contains tests for rendering requests as curl commands and for
+// logging failed requests with WithCurlOnError.
+package httpc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRequestBuilder_Curl(t *testing.T) {
+	client := NewClient(
+		WithBaseURL("https://api.example.com"),
+		WithHeader("X-Client", "httpc"),
+	)
+
+	rb := client.NewRequest().
+		Method(http.MethodPost).
+		URL("/users").
+		Query("page", "2").
+		Header("Content-Type", ContentTypeJSON).
+		Header("X-Note", "it's").
+		Body(strings.NewReader(`{"name":"john"}`))
+
+	cmd, err := rb.Curl()
+	if err != nil {
+		t.Fatalf("Curl() failed: %v", err)
+	}
+
+	expected := []string{
+		"curl -X POST 'https://api.example.com/users?page=2'",
+		"-H 'Content-Type: application/json'",
+		"-H 'X-Client: httpc'",
+		`-H 'X-Note: it'\''s'`,
+		"-H 'User-Agent: go-httpc/1.0'",
+		`--data-raw '{"name":"john"}'`,
+	}
+	for _, part := range expected {
+		if !strings.Contains(cmd, part) {
+			t.Errorf("Expected %q in curl command:\n%s", part, cmd)
+		}
+	}
+
+	body, _ := io.ReadAll(rb.body)
+	if string(body) != `{"name":"john"}` {
+		t.Errorf("Expected the body to be kept for Do, got %q", body)
+	}
+}
+
+func TestRequestBuilder_Curl_GET(t *testing.T) {
+	client := NewClient()
+
+	cmd, err := client.NewRequest().Method(http.MethodGet).URL("https://api.example.com/users").Curl()
+	if err != nil {
+		t.Fatalf("Curl() failed: %v", err)
+	}
+	if !strings.HasPrefix(cmd, "curl 'https://api.example.com/users'") {
+		t.Errorf("Expected GET without -X, got:\n%s", cmd)
+	}
+	if strings.Contains(cmd, "--data-raw") {
+		t.Errorf("Expected no body, got:\n%s", cmd)
+	}
+}
+
+func TestRequestBuilder_Curl_HEAD(t *testing.T) {
+	client := NewClient()
+
+	cmd, err := client.NewRequest().Method(http.MethodHead).URL("https://api.example.com/users").Curl()
+	if err != nil {
+		t.Fatalf("Curl() failed: %v", err)
+	}
+	if !strings.HasPrefix(cmd, "curl -I 'https://api.example.com/users'") || strings.Contains(cmd, "-X HEAD") {
+		t.Errorf("Expected HEAD to be rendered with -I, got:\n%s", cmd)
+	}
+}
+
+func TestRequestBuilder_Curl_LargeBody(t *testing.T) {
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		received.Store(n)
+	}))
+	defer server.Close()
+
+	payload := strings.Repeat("x", curlMaxBodySize+1)
+	for name, body := range map[string]io.Reader{
+		"stream": streamReader{strings.NewReader(payload)},
+		"seeker": strings.NewReader(payload),
+		"buffer": bytes.NewBufferString(payload),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rb := NewClient().NewRequest().Method(http.MethodPut).URL(server.URL).Body(body)
+
+			cmd, err := rb.Curl()
+			if err != nil {
+				t.Fatalf("Curl() failed: %v", err)
+			}
+			if strings.Contains(cmd, "--data-raw") || !strings.HasSuffix(cmd, curlBodyOmitted) {
+				t.Errorf("Expected the large body to be omitted, got:\n%.200s", cmd)
+			}
+
+			if _, err := rb.Do(); err != nil {
+				t.Fatalf("Do() failed: %v", err)
+			}
+			if received.Load() != int64(len(payload)) {
+				t.Errorf("Expected the whole body to be sent, got %d bytes", received.Load())
+			}
+		})
+	}
+}
+
+func TestRequestBuilder_CurlRedacted(t *testing.T) {
+	client := NewClient(
+		WithHeader("Authorization", "Bearer header-secret"),
+		WithRedaction(RedactionPolicy{
+			Headers:     []string{"authorization"},
+			QueryParams: []string{"token"},
+			BodyFields:  []string{"password"},
+		}),
+	)
+
+	rb := client.NewRequest().
+		Method(http.MethodPost).
+		URL("https://api.example.com/login?token=query-secret").
+		JSON(map[string]string{"user": "john", "password": "body-secret"})
+
+	cmd, err := rb.CurlRedacted()
+	if err != nil {
+		t.Fatalf("CurlRedacted() failed: %v", err)
+	}
+	for _, secret := range []string{"header-secret", "query-secret", "body-secret"} {
+		if strings.Contains(cmd, secret) {
+			t.Errorf("Expected %q to be masked:\n%s", secret, cmd)
+		}
+	}
+	if !strings.Contains(cmd, "john") {
+		t.Errorf("Expected non-sensitive fields to be kept:\n%s", cmd)
+	}
+
+	plain, _ := rb.Curl()
+	if !strings.Contains(plain, "body-secret") {
+		t.Errorf("Expected Curl not to mask values:\n%s", plain)
+	}
+}
+
+func TestRequestBuilder_Curl_Error(t *testing.T) {
+	client := NewClient()
+
+	_, err := client.NewRequest().URL("https://api.example.com").JSON(make(chan int)).Curl()
+	if err == nil {
+		t.Error("Expected the build error to be returned")
+	}
+}
+
+func TestResponse_Curl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithAuthorization("auth-secret"))
+
+	resp, err := client.NewRequest().
+		Method(http.MethodPut).
+		URL(server.URL + "/users/1").
+		JSON(map[string]string{"name": "john"}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	cmd := resp.Curl()
+	for _, part := range []string{"-X PUT", "/users/1'", "-H 'Authorization: Bearer auth-secret'", `--data-raw '{"name":"john"}'`} {
+		if !strings.Contains(cmd, part) {
+			t.Errorf("Expected %q in curl command:\n%s", part, cmd)
+		}
+	}
+
+	if redacted := resp.CurlRedacted(); strings.Contains(redacted, "auth-secret") {
+		t.Errorf("Expected Authorization to be masked:\n%s", redacted)
+	}
+}
+
+func TestClient_WithCurlOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var logBuf bytes.Buffer
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithCurlOnError(log.New(&logBuf, "", 0)),
+	)
+
+	if _, err := client.Get("/ok"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if logBuf.Len() != 0 {
+		t.Errorf("Expected successful requests not to be logged, got %q", logBuf.String())
+	}
+
+	// A streamed body is captured as it is sent
+	_, err := client.NewRequest().
+		Method(http.MethodPost).
+		URL("/fail").
+		Body(io.NopCloser(strings.NewReader("password=hunter2&user=john"))).
+		Header("Content-Type", "application/x-www-form-urlencoded").
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	output := logBuf.String()
+	if !strings.Contains(output, "status 400") || !strings.Contains(output, "curl -X POST") {
+		t.Errorf("Expected failed request to be logged as curl, got %q", output)
+	}
+	if !strings.Contains(output, "user=john") || strings.Contains(output, "hunter2") {
+		t.Errorf("Expected captured body with password masked, got %q", output)
+	}
+}
+
+func TestClient_WithCurlOnError_TransportError(t *testing.T) {
+	var logBuf bytes.Buffer
+	client := NewClient(
+		WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			})
+		}),
+		WithCurlOnError(log.New(&logBuf, "", 0)),
+	)
+
+	if _, err := client.Get("https://api.example.com/users"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !strings.Contains(logBuf.String(), "connection refused") ||
+		!strings.Contains(logBuf.String(), "curl 'https://api.example.com/users'") {
+		t.Errorf("Expected transport error to be logged as curl, got %q", logBuf.String())
+	}
+}
@@ -8,17 +8,20 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 )
 
 // DebugTransport is a wrapper around http.DefaultTransport that logs HTTP requests and responses.
+// Sensitive headers, query parameters and body fields are masked according to
+// Redaction, in addition to DefaultRedactionPolicy; when it is nil, the Client
+// policy set with WithRedaction is used, or DefaultRedactionPolicy.
 type DebugTransport struct {
 	transport   http.RoundTripper
 	Debug       bool
 	Logger      *log.Logger
 	LogBody     bool
 	MaxBodySize int64
+	Redaction   *RedactionPolicy
 }
 
 // NewDebugTransport creates a new DebugTransport with the given options.
@@ -44,15 +47,19 @@ func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if !t.Debug {
 		return t.transport.RoundTrip(req)
 	}
+	policy := redactionFrom(req.Context(), t.Redaction)
+	id := requestIDSuffix(req.Context())
+
 	//Log the request
-	t.Logger.Printf("→ %s %s", req.Method, req.URL)
-	t.logHeaders("Request Headers", req.Header)
+	t.Logger.Printf("→ %s %s%s", req.Method, policy.RedactURL(req.URL), id)
+	t.logHeaders("Request Headers", req.Header, policy)
 
 	if t.LogBody && req.Body != nil {
 		body, err := io.ReadAll(io.LimitReader(req.Body, t.MaxBodySize))
 		if err == nil {
 			req.Body = io.NopCloser(bytes.NewBuffer(body))
 			decodedBody := t.decodeBody(body, req.Header.Get("Content-Encoding"))
+			decodedBody = policy.RedactBody(decodedBody, req.Header.Get("Content-Type"))
 			t.Logger.Printf("Request Body:\n%s", string(decodedBody))
 		}
 	}
@@ -62,19 +69,20 @@ func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	resp, err := t.transport.RoundTrip(req)
 	duration := time.Since(start)
 	if err != nil {
-		t.Logger.Printf("✗ Error: %v (took %v)", err, duration)
+		t.Logger.Printf("✗ Error: %v (took %v)%s", err, duration, id)
 		return resp, err
 	}
 
 	//Log the response
-	t.Logger.Printf("← %d %s (took %v)", resp.StatusCode, http.StatusText(resp.StatusCode), duration)
-	t.logHeaders("Response Headers", resp.Header)
+	t.Logger.Printf("← %d %s (took %v)%s", resp.StatusCode, http.StatusText(resp.StatusCode), duration, id)
+	t.logHeaders("Response Headers", resp.Header, policy)
 
 	if t.LogBody && resp.Body != nil {
 		body, err := io.ReadAll(io.LimitReader(resp.Body, t.MaxBodySize))
 		if err == nil {
 			resp.Body = io.NopCloser(bytes.NewBuffer(body))
 			decodedBody := t.decodeBody(body, resp.Header.Get("Content-Encoding"))
+			decodedBody = policy.RedactBody(decodedBody, resp.Header.Get("Content-Type"))
 			t.Logger.Printf("Response Body:\n%s", string(decodedBody))
 		}
 	}
@@ -83,11 +91,11 @@ func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 }
 
 // logHeaders logs the headers in the given title.
-// It filters out sensitive headers.
+// It masks sensitive headers according to policy.
 // It logs each header key and value on a separate line.
 // It ignores empty headers.
 
-func (t *DebugTransport) logHeaders(title string, headers http.Header) {
+func (t *DebugTransport) logHeaders(title string, headers http.Header, policy *RedactionPolicy) {
 	if len(headers) == 0 {
 		return
 	}
@@ -96,27 +104,9 @@ func (t *DebugTransport) logHeaders(title string, headers http.Header) {
 
 	for key, values := range headers {
 		for _, value := range values {
-			if t.isSensitive(key) {
-				value = "***MODIFIED***SENSITIVE HEADER***"
-			}
-			t.Logger.Printf("	%s: %s", key, value)
-		}
-	}
-}
-
-// isSensitive returns true if the header is considered sensitive and should not be logged.
-// It checks against a list of common sensitive headers.
-// It is case-insensitive.
-func (t *DebugTransport) isSensitive(header string) bool {
-	sensitiveHeaders := []string{"authorization", "api-key", "x-api-key", "cookie"}
-	lowerCaseHeader := strings.ToLower(header)
-	for _, sensitiveHeader := range sensitiveHeaders {
-		if strings.Contains(lowerCaseHeader, sensitiveHeader) {
-			return true
+			t.Logger.Printf("	%s: %s", key, policy.RedactHeader(key, value))
 		}
 	}
-
-	return false
 }
 
 // decodeBody decodes the body based on the Content-Encoding header.
@@ -6,6 +6,7 @@ package httpc
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -273,6 +274,8 @@ func TestDebugTransport_HandlesErrors(t *testing.T) {
 
 func TestDebugTransport_IsSensitive(t *testing.T) {
 	dt := NewDebugTransport(nil, true)
+	dt.Redaction = &RedactionPolicy{QueryParams: []string{"signature"}}
+	policy := redactionFrom(context.Background(), dt.Redaction)
 
 	tests := []struct {
 		header   string
@@ -293,9 +296,9 @@ func TestDebugTransport_IsSensitive(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.header, func(t *testing.T) {
-			got := dt.isSensitive(tt.header)
+			got := policy.IsSensitiveHeader(tt.header)
 			if got != tt.expected {
-				t.Errorf("isSensitive(%q) = %v, want %v", tt.header, got, tt.expected)
+				t.Errorf("IsSensitiveHeader(%q) = %v, want %v", tt.header, got, tt.expected)
 			}
 		})
 	}
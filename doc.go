@@ -4,7 +4,7 @@
 // # Features
 //
 //   - Fluent API with chainable methods for building requests
-//   - Automatic retry with configurable exponential backoff
+//   - Automatic retry with configurable, optionally jittered backoff
 //   - Interceptor pattern for request modification (auth, logging, rate limiting, etc.)
 //   - Built-in JSON marshaling and unmarshaling
 //   - Full context.Context support with cancellation, timeouts, and deadlines
@@ -82,7 +82,7 @@
 //   - WithBaseAuth(user, pass): HTTP Basic authentication
 //   - WithApiKey(header, key): API key authentication
 //   - WithUserAgent(ua): Sets User-Agent header
-//   - WithRequestId(header): Adds unique request IDs
+//   - WithRequestId(header): Adds a unique ID to each request
 //   - WithLogger(logger): Request/response logging
 //   - WithDebug(): Debug mode with detailed logging
 //   - WithBlockedList(domains): Blocks requests to specific domains
@@ -97,11 +97,12 @@
 //
 // # Retry Logic
 //
-// Configure automatic retries with exponential backoff:
+// Configure automatic retries with backoff:
 //
 //	retryConfig := httpc.DefaultRetryConfig()
 //	retryConfig.MaxRetries = 3
 //	retryConfig.Backoff = time.Second
+//	retryConfig.BackoffStrategy = httpc.FullJitterBackoff(100*time.Millisecond, 10*time.Second)
 //
 //	client := httpc.NewClient(
 //		httpc.WithRetry(*retryConfig),
@@ -113,6 +114,9 @@
 //   - 5xx server errors
 //   - 429 (rate limit) responses
 //
+// Only idempotent methods are retried unless RetryConfig.RetryNonIdempotent is set
+// or RetryConfig.IdempotencyKeyHeader attaches a stable key to each request.
+//
 // # Error Handling
 //
 // Check for both network errors and HTTP errors:
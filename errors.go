@@ -7,6 +7,22 @@ import (
 	"net"
 )
 
+// ErrBodyNotReplayable is returned when a failed request should be retried but its
+// body was streamed and cannot be sent again. It wraps the error of the last attempt.
+// When the last attempt received a response, such as a 503, that response is
+// returned without an error instead: like any response once retries are
+// exhausted, its status code is the outcome of the request, and Response.Attempts
+// reports that it was not retried.
+// Use RetryConfig.MaxBufferedBodySize or a body with http.Request.GetBody to allow retries.
+//
+// Example:
+//
+//	_, err := client.NewRequest().Method("PUT").URL("/upload").Body(file).Do()
+//	if errors.Is(err, httpc.ErrBodyNotReplayable) {
+//		log.Println("upload failed and could not be retried")
+//	}
+var ErrBodyNotReplayable = errors.New("request body is not replayable")
+
 // Error represents an HTTP error response from the client.
 // It contains the HTTP status code, error message, and response body.
 //
@@ -0,0 +1,515 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the HAR (HTTP Archive 1.2) capture transport and recorder.
+package httpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrHARRecorderClosed is returned by HARRecorder.Flush after Close.
+var ErrHARRecorderClosed = errors.New("HAR recorder is closed")
+
+// HARConfig configures a HARRecorder.
+type HARConfig struct {
+	// MaxBodySize is the maximum number of bytes of each request and response
+	// body stored in the archive. Longer bodies are truncated. If zero, 1 MB is used.
+	MaxBodySize int64
+
+	// Redaction masks sensitive headers, query parameters and body fields.
+	// If nil, the Client policy set with WithRedaction is used, or
+	// DefaultRedactionPolicy, as for DebugTransport.
+	Redaction *RedactionPolicy
+}
+
+// defaultHARMaxBodySize is used when HARConfig.MaxBodySize is not set.
+const defaultHARMaxBodySize = 1 << 20
+
+// HARRecorder records the exchanges of a Client into an HTTP Archive (HAR 1.2)
+// that can be opened in browser developer tools. Attach it with WithHAR.
+// An exchange is recorded once its response body has been closed or fully read.
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	recorder := httpc.NewHARRecorder("partner.har", httpc.HARConfig{})
+//	defer recorder.Close()
+//
+//	client := httpc.NewClient(httpc.WithHAR(recorder))
+type HARRecorder struct {
+	path   string
+	config HARConfig
+
+	mu      sync.Mutex
+	entries []harEntry
+	closed  bool
+}
+
+// NewHARRecorder creates a HARRecorder that writes to the file at path on
+// Flush and Close. If path is empty, the archive is only available through
+// WriteTo.
+func NewHARRecorder(path string, config HARConfig) *HARRecorder {
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultHARMaxBodySize
+	}
+	return &HARRecorder{path: path, config: config}
+}
+
+// Len returns the number of recorded exchanges.
+func (r *HARRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.entries)
+}
+
+// WriteTo writes the archive of all exchanges recorded so far to w as JSON.
+// It implements io.WriterTo.
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := r.marshal()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Flush writes the archive of all exchanges recorded so far to the file,
+// replacing its previous content. It is a no-op if the recorder has no path.
+func (r *HARRecorder) Flush() error {
+	r.mu.Lock()
+	closed := r.closed
+	r.mu.Unlock()
+
+	if closed {
+		return ErrHARRecorderClosed
+	}
+	return r.flush()
+}
+
+// Close flushes the archive and stops recording. Exchanges completing after
+// Close are dropped.
+func (r *HARRecorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	return r.flush()
+}
+
+// flush writes the archive to the file through a temporary file, so that
+// readers never observe a partially written archive.
+func (r *HARRecorder) flush() error {
+	if r.path == "" {
+		return nil
+	}
+
+	data, err := r.marshal()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
+
+// marshal encodes the recorded exchanges, ordered by start time, as a HAR document.
+func (r *HARRecorder) marshal() ([]byte, error) {
+	r.mu.Lock()
+	entries := append([]harEntry(nil), r.entries...)
+	r.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime < entries[j].StartedDateTime
+	})
+
+	return json.MarshalIndent(harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "httpc", Version: "1.0"},
+		Entries: entries,
+	}}, "", "  ")
+}
+
+// add records a completed exchange.
+func (r *HARRecorder) add(entry harEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.closed {
+		r.entries = append(r.entries, entry)
+	}
+}
+
+// harTransport is an http.RoundTripper that records exchanges into a HARRecorder.
+type harTransport struct {
+	transport http.RoundTripper
+	recorder  *HARRecorder
+}
+
+// RoundTrip implements http.RoundTripper by capturing the request, its
+// response and their timings. Bodies are captured as they are read, so
+// streaming is not affected.
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := t.recorder.config.MaxBodySize
+
+	var reqBody *captureBody
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = &captureBody{ReadCloser: req.Body, limit: limit}
+		req = req.Clone(req.Context())
+		req.Body = reqBody
+	}
+
+	timings := &timingRecorder{}
+	timings.begin()
+	started := time.Now()
+
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), timings.trace()))
+	resp, err := t.transport.RoundTrip(traced)
+	if err != nil {
+		// Failed exchanges are recorded too, with the error in place of a response
+		timings.finish()
+		t.recorder.add(t.entry(req, reqBody, nil, nil, err, started, timings.snapshot()))
+		return resp, err
+	}
+
+	complete := func(respBody *captureBody) {
+		timings.finish()
+		t.recorder.add(t.entry(req, reqBody, resp, respBody, nil, started, timings.snapshot()))
+	}
+
+	if resp.Body == nil {
+		complete(nil)
+		return resp, nil
+	}
+
+	respBody := &captureBody{ReadCloser: resp.Body, limit: limit}
+	respBody.done = func() { complete(respBody) }
+	resp.Body = respBody
+	return resp, nil
+}
+
+// entry builds the HAR entry of a completed exchange, applying redaction.
+// When the exchange failed, resp is nil and the entry has an empty response
+// with status 0 carrying err in its _error field, as browsers record them.
+func (t *harTransport) entry(req *http.Request, reqBody *captureBody, resp *http.Response,
+	respBody *captureBody, err error, started time.Time, timings *Timings) harEntry {
+	policy := redactionFrom(req.Context(), t.recorder.config.Redaction)
+
+	request := harRequest{
+		Method:      req.Method,
+		URL:         policy.RedactURL(req.URL),
+		HTTPVersion: req.Proto,
+		Cookies:     []harCookie{},
+		Headers:     harHeaders(req.Header, policy),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if request.HTTPVersion == "" {
+		request.HTTPVersion = "HTTP/1.1"
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			if containsFold(policy.QueryParams, name) {
+				value = policy.mask(redactedValueMask)
+			}
+			request.QueryString = append(request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+	sort.Slice(request.QueryString, func(i, j int) bool {
+		return request.QueryString[i].Name < request.QueryString[j].Name
+	})
+	if reqBody != nil {
+		body, size, _ := reqBody.captured()
+		contentType := req.Header.Get("Content-Type")
+		text, _ := harBodyText(policy.RedactBody(body, contentType))
+		request.BodySize = size
+		request.PostData = &harPostData{MimeType: contentType, Text: text}
+	}
+
+	if resp == nil {
+		return harEntry{
+			StartedDateTime: started.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Time:            harTimingsFrom(timings).total(),
+			Request:         request,
+			Response: harResponse{
+				Cookies:     []harCookie{},
+				Headers:     []harNameValue{},
+				HeadersSize: -1,
+				BodySize:    -1,
+				Error:       err.Error(),
+			},
+			Cache:   struct{}{},
+			Timings: harTimingsFrom(timings),
+		}
+	}
+
+	response := harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harCookie{},
+		Headers:     harHeaders(resp.Header, policy),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		Content:     harContent{MimeType: resp.Header.Get("Content-Type")},
+	}
+	if respBody != nil {
+		body, size, truncated := respBody.captured()
+		if isGzipEncoded(resp.Header.Get("Content-Encoding")) && !truncated {
+			if decoded, err := decodeGzipBody(body); err == nil {
+				body = decoded
+			}
+		}
+		response.BodySize = size
+		response.Content.Size = int64(len(body))
+		response.Content.Text, response.Content.Encoding = harBodyText(policy.RedactBody(body, response.Content.MimeType))
+		if truncated {
+			response.Content.Comment = "truncated"
+		}
+	}
+
+	entry := harEntry{
+		StartedDateTime: started.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Request:         request,
+		Response:        response,
+		Cache:           struct{}{},
+		Timings:         harTimingsFrom(timings),
+	}
+	entry.Time = entry.Timings.total()
+	return entry
+}
+
+// harHeaders converts headers to HAR name/value pairs with sensitive values masked.
+func harHeaders(headers http.Header, policy *RedactionPolicy) []harNameValue {
+	pairs := make([]harNameValue, 0, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			pairs = append(pairs, harNameValue{Name: name, Value: policy.RedactHeader(name, value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+// harBodyText returns body as text, base64-encoding it when it is not valid UTF-8.
+func harBodyText(body []byte) (text, encoding string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// harTimingsFrom converts phase timings to HAR timings in milliseconds, where
+// -1 marks a phase that does not apply.
+func harTimingsFrom(t *Timings) harTimings {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	optional := func(d time.Duration) float64 {
+		if d <= 0 {
+			return -1
+		}
+		return ms(d)
+	}
+
+	// HAR counts the TLS handshake as part of connect
+	connect := optional(t.Connect + t.TLSHandshake)
+	send := ms(t.Total - t.DNS - t.Connect - t.TLSHandshake - t.TimeToFirstByte - t.BodyRead)
+	if send < 0 {
+		send = 0
+	}
+
+	return harTimings{
+		Blocked: -1,
+		DNS:     optional(t.DNS),
+		Connect: connect,
+		SSL:     optional(t.TLSHandshake),
+		Send:    send,
+		Wait:    ms(t.TimeToFirstByte),
+		Receive: ms(t.BodyRead),
+	}
+}
+
+// captureBody keeps a bounded copy of a body as it is read and calls done
+// once, when the body is closed or fully read. A request body is read by the
+// transport while the entry may be built on another goroutine, so the copy is
+// guarded by mu.
+type captureBody struct {
+	io.ReadCloser
+	limit int64
+	done  func()
+	once  sync.Once
+
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	size      int64
+	truncated bool
+}
+
+// Read reads from the underlying body and captures up to limit bytes.
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.mu.Lock()
+		b.size += int64(n)
+		if room := b.limit - int64(b.buf.Len()); room > 0 {
+			b.buf.Write(p[:min(int64(n), room)])
+		}
+		if b.size > b.limit {
+			b.truncated = true
+		}
+		b.mu.Unlock()
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+// captured returns a copy of the captured bytes, the total size read, and
+// whether the copy was truncated.
+func (b *captureBody) captured() ([]byte, int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return bytes.Clone(b.buf.Bytes()), b.size, b.truncated
+}
+
+// Close closes the underlying body and completes the capture.
+func (b *captureBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+// finish calls done once.
+func (b *captureBody) finish() {
+	if b.done != nil {
+		b.once.Do(b.done)
+	}
+}
+
+// HAR 1.2 document structure, see http://www.softwareishard.com/blog/har-12-spec/.
+type (
+	harDocument struct {
+		Log harLog `json:"log"`
+	}
+
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+	}
+
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harCookie    `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int64          `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harCookie    `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int64          `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+		Error       string         `json:"_error,omitempty"`
+	}
+
+	harCookie struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+
+	harContent struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+		Comment  string `json:"comment,omitempty"`
+	}
+
+	harTimings struct {
+		Blocked float64 `json:"blocked"`
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"`
+		SSL     float64 `json:"ssl"`
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// total returns the total time of the entry, the sum of the applicable phases.
+// The ssl phase is already included in connect.
+func (t harTimings) total() float64 {
+	total := 0.0
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if phase > 0 {
+			total += phase
+		}
+	}
+	return total
+}
@@ -0,0 +1,235 @@
+// Package httpc provides tests for HAR capture.
+// This file contains tests for the HAR recorder, including bodies,
+// truncation, redaction, timings, and flushing to disk.
+package httpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readHAR decodes the archive recorded so far.
+func readHAR(t *testing.T, recorder *HARRecorder) harDocument {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := recorder.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() failed: %v", err)
+	}
+
+	var doc harDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid HAR document: %v", err)
+	}
+	return doc
+}
+
+func TestClient_WithHAR(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder("", HARConfig{})
+	client := NewClient(WithBaseURL(server.URL), WithHAR(recorder))
+
+	resp, err := client.NewRequest().
+		Method(http.MethodPost).
+		URL("/users").
+		Query("page", "2").
+		Header("X-Trace", "abc").
+		JSON(map[string]string{"name": "john"}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	if recorder.Len() != 0 {
+		t.Error("Expected the exchange to be recorded only once the body is read")
+	}
+	_, _ = resp.Bytes()
+
+	doc := readHAR(t, recorder)
+	if doc.Log.Version != "1.2" {
+		t.Errorf("Expected HAR version 1.2, got %q", doc.Log.Version)
+	}
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(doc.Log.Entries))
+	}
+
+	entry := doc.Log.Entries[0]
+	if entry.Request.Method != http.MethodPost || !strings.HasSuffix(entry.Request.URL, "/users?page=2") {
+		t.Errorf("Unexpected request %s %s", entry.Request.Method, entry.Request.URL)
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0].Value != "2" {
+		t.Errorf("Expected query string page=2, got %v", entry.Request.QueryString)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != `{"name":"john"}` {
+		t.Errorf("Expected request body to be captured, got %+v", entry.Request.PostData)
+	}
+
+	foundHeader := false
+	for _, header := range entry.Request.Headers {
+		if header.Name == "X-Trace" && header.Value == "abc" {
+			foundHeader = true
+		}
+	}
+	if !foundHeader {
+		t.Errorf("Expected X-Trace header, got %v", entry.Request.Headers)
+	}
+
+	if entry.Response.Status != http.StatusCreated || entry.Response.Content.Text != `{"id":1}` {
+		t.Errorf("Unexpected response %d %q", entry.Response.Status, entry.Response.Content.Text)
+	}
+	if entry.Response.Content.MimeType != "application/json" {
+		t.Errorf("Expected response mime type, got %q", entry.Response.Content.MimeType)
+	}
+	if entry.Timings.Connect <= 0 || entry.Timings.Wait < 0 || entry.Time <= 0 {
+		t.Errorf("Expected timings for a new connection, got %+v (time %v)", entry.Timings, entry.Time)
+	}
+	if entry.Timings.SSL != -1 {
+		t.Errorf("Expected ssl to be -1 over plain HTTP, got %v", entry.Timings.SSL)
+	}
+}
+
+func TestClient_WithHAR_TransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serverURL := server.URL
+	server.Close() // Requests will fail with connection refused
+
+	recorder := NewHARRecorder("", HARConfig{})
+	client := NewClient(WithHAR(recorder))
+
+	if _, err := client.Get(serverURL + "/users"); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+
+	doc := readHAR(t, recorder)
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("Expected the failed exchange to be recorded, got %d entries", len(doc.Log.Entries))
+	}
+
+	entry := doc.Log.Entries[0]
+	if !strings.HasSuffix(entry.Request.URL, "/users") {
+		t.Errorf("Expected the request to be recorded, got %q", entry.Request.URL)
+	}
+	if entry.Response.Status != 0 || !strings.Contains(entry.Response.Error, "refused") {
+		t.Errorf("Expected an empty response with the error, got %d %q", entry.Response.Status, entry.Response.Error)
+	}
+}
+
+func TestClient_WithHAR_Redaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"server-secret"}`))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder("", HARConfig{})
+	client := NewClient(WithHAR(recorder), WithAuthorization("auth-secret"))
+
+	resp, err := client.NewRequest().
+		Method(http.MethodPost).
+		URL(server.URL + "?token=query-secret").
+		JSON(map[string]string{"password": "body-secret"}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	var buf bytes.Buffer
+	_, _ = recorder.WriteTo(&buf)
+	for _, secret := range []string{"auth-secret", "query-secret", "body-secret", "server-secret"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("Expected %q to be redacted from the archive", secret)
+		}
+	}
+}
+
+func TestClient_WithHAR_Truncation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder("", HARConfig{MaxBodySize: 10})
+	client := NewClient(WithHAR(recorder))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	body, _ := resp.Bytes()
+	if len(body) != 100 {
+		t.Fatalf("Capture must not affect the body read by the caller, got %d bytes", len(body))
+	}
+
+	content := readHAR(t, recorder).Log.Entries[0].Response
+	if content.Content.Text != strings.Repeat("x", 10) || content.Content.Comment != "truncated" {
+		t.Errorf("Expected a truncated body, got %+v", content.Content)
+	}
+	if content.BodySize != 100 {
+		t.Errorf("Expected body size 100, got %d", content.BodySize)
+	}
+}
+
+func TestHARRecorder_FlushAndClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte{0xff, 0xfe})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "capture.har")
+	recorder := NewHARRecorder(path, HARConfig{})
+	client := NewClient(WithHAR(recorder))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	var doc harDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid HAR file: %v", err)
+	}
+	if content := doc.Log.Entries[0].Response.Content; content.Encoding != "base64" || content.Text != "//4=" {
+		t.Errorf("Expected binary body to be base64-encoded, got %+v", content)
+	}
+
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	_ = json.Unmarshal(data, &doc)
+	if len(doc.Log.Entries) != 2 {
+		t.Errorf("Expected Close to flush 2 entries, got %d", len(doc.Log.Entries))
+	}
+
+	if err := recorder.Flush(); !errors.Is(err, ErrHARRecorderClosed) {
+		t.Errorf("Expected ErrHARRecorderClosed after Close, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the hedging transport used to reduce tail latency.
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HedgeConfig configures hedged requests. When a request has not completed
+// within Delay, another copy is sent, up to MaxHedges extra copies. The first
+// successful response wins and the remaining copies are cancelled.
+//
+// Hedging multiplies load on the upstream and must only be used for requests
+// that are safe to send more than once, typically GETs against replicated services.
+type HedgeConfig struct {
+	// Delay is how long to wait for a response before sending the next copy.
+	// If zero or negative, 100ms is used.
+	Delay time.Duration
+
+	// MaxHedges is the maximum number of extra copies sent per request.
+	// If zero, one extra copy is sent.
+	MaxHedges int
+
+	// MaxInFlight caps the number of extra copies in flight at once across all
+	// requests of the Client. When the cap is reached, requests simply wait for
+	// their earlier copies instead of hedging. If zero, there is no cap.
+	MaxInFlight int
+
+	// HedgeIf decides whether a request may be hedged. If nil, only GET and
+	// HEAD requests whose body (if any) can be replayed are hedged.
+	HedgeIf func(*http.Request) bool
+}
+
+// defaultHedgeDelay is the hedging delay used when HedgeConfig.Delay is not set.
+const defaultHedgeDelay = 100 * time.Millisecond
+
+// hedgeTransport is an http.RoundTripper that sends additional copies of slow
+// requests and returns the first successful response.
+type hedgeTransport struct {
+	transport http.RoundTripper
+	config    HedgeConfig
+	inFlight  chan struct{}
+}
+
+// hedgeResult is the outcome of one copy of a hedged request.
+type hedgeResult struct {
+	index int
+	resp  *http.Response
+	err   error
+}
+
+// newHedgeTransport creates a hedgeTransport with its own in-flight limit,
+// filling in configuration defaults.
+func newHedgeTransport(rt http.RoundTripper, config HedgeConfig) *hedgeTransport {
+	if config.Delay <= 0 {
+		config.Delay = defaultHedgeDelay
+	}
+	if config.MaxHedges <= 0 {
+		config.MaxHedges = 1
+	}
+
+	t := &hedgeTransport{transport: rt, config: config}
+	if config.MaxInFlight > 0 {
+		t.inFlight = make(chan struct{}, config.MaxInFlight)
+	}
+	return t
+}
+
+// canHedge reports whether req is eligible for hedging.
+func (t *hedgeTransport) canHedge(req *http.Request) bool {
+	if !isReplayable(req) {
+		return false
+	}
+	if t.config.HedgeIf != nil {
+		return t.config.HedgeIf(req)
+	}
+	return req.Method == "" || req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// acquire reserves an in-flight slot for an extra copy without blocking.
+func (t *hedgeTransport) acquire() bool {
+	if t.inFlight == nil {
+		return true
+	}
+	select {
+	case t.inFlight <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees an in-flight slot taken by acquire.
+func (t *hedgeTransport) release() {
+	if t.inFlight != nil {
+		<-t.inFlight
+	}
+}
+
+// RoundTrip implements http.RoundTripper by sending the request and, if it has
+// not completed within HedgeConfig.Delay, additional copies of it.
+// A response with a status below 500 is a success; the first one is returned
+// and all other copies are cancelled. Copies are only sent when the delay
+// expires, never in reaction to a failure, so hedging does not turn into
+// immediate retries during an outage: once every copy sent so far has failed,
+// the last failure is returned.
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.canHedge(req) {
+		return t.transport.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	results := make(chan hedgeResult, t.config.MaxHedges+1)
+	cancels := make([]context.CancelFunc, 0, t.config.MaxHedges+1)
+
+	send := func(copyReq *http.Request, hedge bool) {
+		copyCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			if hedge {
+				defer t.release()
+			}
+			// Each copy gets its own headers, which inner transports may modify
+			resp, err := t.transport.RoundTrip(copyReq.Clone(copyCtx))
+			results <- hedgeResult{index: index, resp: resp, err: err}
+		}()
+	}
+
+	// hedge sends another copy if the limits allow it
+	hedge := func() bool {
+		if len(cancels) > t.config.MaxHedges || !t.acquire() {
+			return false
+		}
+		copyReq, err := rewindBody(req)
+		if err != nil {
+			t.release()
+			return false
+		}
+		send(copyReq, true)
+		return true
+	}
+
+	send(req, false)
+	pending := 1
+
+	timer := time.NewTimer(t.config.Delay)
+	defer timer.Stop()
+	timerC := timer.C
+
+	last := hedgeResult{index: -1}
+	for pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+			if res.err == nil && res.resp.StatusCode < http.StatusInternalServerError {
+				t.discard(last, cancels)
+				t.abandon(res.index, cancels, results, pending)
+				return withCancelOnClose(res.resp, cancels[res.index]), nil
+			}
+
+			t.discard(last, cancels)
+			last = res
+
+		case <-timerC:
+			if hedge() {
+				pending++
+			}
+			// No more copies may be sent once MaxHedges is reached
+			if len(cancels) > t.config.MaxHedges {
+				timerC = nil
+				continue
+			}
+			timer.Reset(t.config.Delay)
+
+		case <-ctx.Done():
+			t.discard(last, cancels)
+			t.abandon(-1, cancels, results, pending)
+			return nil, ctx.Err()
+		}
+	}
+
+	if last.err != nil {
+		cancels[last.index]()
+		return nil, last.err
+	}
+	return withCancelOnClose(last.resp, cancels[last.index]), nil
+}
+
+// discard releases a failed copy that is no longer the candidate result.
+func (t *hedgeTransport) discard(res hedgeResult, cancels []context.CancelFunc) {
+	if res.index < 0 {
+		return
+	}
+	discardResponse(res.resp)
+	cancels[res.index]()
+}
+
+// abandon cancels every copy except the winner and discards the responses of
+// the copies that are still pending once they arrive.
+func (t *hedgeTransport) abandon(winner int, cancels []context.CancelFunc, results <-chan hedgeResult, pending int) {
+	for i, cancel := range cancels {
+		if i != winner {
+			cancel()
+		}
+	}
+
+	go func() {
+		for i := 0; i < pending; i++ {
+			res := <-results
+			discardResponse(res.resp)
+		}
+	}()
+}
+
+// withCancelOnClose ties cancel to the response body, so the copy's context
+// stays alive while the caller reads the body.
+func withCancelOnClose(resp *http.Response, cancel context.CancelFunc) *http.Response {
+	if resp.Body == nil {
+		cancel()
+		return resp
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp
+}
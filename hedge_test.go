@@ -0,0 +1,250 @@
+// Package httpc provides tests for hedged requests.
+// This file contains tests for hedging delays, winner selection,
+// in-flight limits, and eligibility of requests.
+package httpc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WithHedging_FastResponseNoHedge(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(HedgeConfig{Delay: 200 * time.Millisecond}))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if callCount.Load() != 1 {
+		t.Errorf("Expected 1 call, got %d", callCount.Load())
+	}
+}
+
+func TestClient_WithHedging_SlowPrimaryHedged(t *testing.T) {
+	var callCount atomic.Int32
+	var cancelled atomic.Bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if callCount.Add(1) == 1 {
+			// The primary hangs until it is cancelled
+			select {
+			case <-r.Context().Done():
+				cancelled.Store(true)
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("hedge"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(HedgeConfig{Delay: 50 * time.Millisecond}))
+
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("String() failed: %v", err)
+	}
+
+	if body != "hedge" {
+		t.Errorf("Expected hedged response body, got %q", body)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected hedge to answer quickly, took %v", elapsed)
+	}
+
+	if callCount.Load() != 2 {
+		t.Errorf("Expected 2 calls, got %d", callCount.Load())
+	}
+
+	// The losing copy is cancelled
+	deadline := time.Now().Add(time.Second)
+	for !cancelled.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected the slow primary to be cancelled")
+	}
+}
+
+func TestClient_WithHedging_MaxHedges(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		time.Sleep(150 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(HedgeConfig{Delay: 20 * time.Millisecond, MaxHedges: 2}))
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if got := callCount.Load(); got != 3 {
+		t.Errorf("Expected 3 calls (1 primary + 2 hedges), got %d", got)
+	}
+}
+
+func TestClient_WithHedging_MaxInFlight(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := newHedgeTransport(defaultTransport(), HedgeConfig{Delay: 10 * time.Millisecond, MaxInFlight: 1})
+	// Occupy the only in-flight slot
+	transport.inFlight <- struct{}{}
+
+	client := NewClient(WithInterceptor(func(http.RoundTripper) http.RoundTripper { return transport }))
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if got := callCount.Load(); got != 1 {
+		t.Errorf("Expected no hedge when the in-flight limit is reached, got %d calls", got)
+	}
+}
+
+func TestClient_WithHedging_PostNotHedged(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(HedgeConfig{Delay: 10 * time.Millisecond}))
+
+	if _, err := client.Post(server.URL, map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("Post() failed: %v", err)
+	}
+
+	if got := callCount.Load(); got != 1 {
+		t.Errorf("Expected POST not to be hedged, got %d calls", got)
+	}
+}
+
+func TestClient_WithHedging_AllFail(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(HedgeConfig{Delay: time.Second, MaxHedges: 1}))
+
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+
+	// A failed copy is returned right away rather than hedged
+	if got := callCount.Load(); got != 1 {
+		t.Errorf("Expected 1 call, got %d", got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the failure without waiting for the delay, took %v", elapsed)
+	}
+}
+
+func TestNewHedgeTransport_DefaultDelay(t *testing.T) {
+	for _, delay := range []time.Duration{0, -time.Second} {
+		transport := newHedgeTransport(defaultTransport(), HedgeConfig{Delay: delay})
+		if transport.config.Delay != defaultHedgeDelay {
+			t.Errorf("Expected Delay %v to default to %v, got %v", delay, defaultHedgeDelay, transport.config.Delay)
+		}
+	}
+}
+
+func TestClient_WithHedging_StopsAfterMaxHedges(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(HedgeConfig{Delay: time.Millisecond, MaxHedges: 1}))
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if got := callCount.Load(); got != 2 {
+		t.Errorf("Expected 2 calls (1 primary + 1 hedge), got %d", got)
+	}
+}
+
+func TestClient_WithHedging_CopiesHaveOwnHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var copies atomic.Int32
+	client := NewClient(
+		// Sets a header on every copy, below the hedging transport
+		WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				for i := 0; i < 100; i++ {
+					req.Header.Set("X-Copy", strconv.Itoa(int(copies.Add(1))))
+				}
+				return rt.RoundTrip(req)
+			})
+		}),
+		WithHedging(HedgeConfig{Delay: time.Millisecond, MaxHedges: 3}),
+	)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if resp.Request.Header.Get("X-Copy") == "" {
+		t.Error("Expected the winning copy to carry its header")
+	}
+}
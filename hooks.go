@@ -0,0 +1,96 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the lifecycle hooks registered with WithOnRequest,
+// WithOnResponse, WithOnError and WithOnRetry.
+package httpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestHook is called with the request built by RequestBuilder before it is
+// sent through the interceptors. It may modify the request in place; returning
+// an error aborts the request, and the error is returned by RequestBuilder.Do.
+type RequestHook func(req *http.Request) error
+
+// ResponseHook is called with the Response of every request that received
+// one, whatever its status, before it is returned by RequestBuilder.Do.
+type ResponseHook func(resp *Response)
+
+// ErrorHook is called with the request and the error of every request that
+// failed without a response, including requests aborted by a RequestHook.
+type ErrorHook func(req *http.Request, err error)
+
+// RetryHook is called before each retry with the same RetryEvent as
+// RetryConfig.OnRetry. It fires for requests retried by WithRetry or by a
+// per-request override (see RequestBuilder.Retry).
+type RetryHook func(event RetryEvent)
+
+// hooks holds the lifecycle hooks of a Client, in registration order.
+type hooks struct {
+	onRequest  []RequestHook
+	onResponse []ResponseHook
+	onError    []ErrorHook
+	onRetry    []RetryHook
+}
+
+// hooksKey is the context key under which doRequest stores the Client hooks
+// for the retry transport.
+type hooksKey struct{}
+
+// hooksFrom returns the hooks stored in ctx, or nil.
+func hooksFrom(ctx context.Context) *hooks {
+	h, _ := ctx.Value(hooksKey{}).(*hooks)
+	return h
+}
+
+// request runs the request hooks in order, stopping at the first error.
+func (h *hooks) request(req *http.Request) error {
+	if h == nil {
+		return nil
+	}
+	for _, hook := range h.onRequest {
+		if err := hook(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// response runs the response hooks in order.
+func (h *hooks) response(resp *Response) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.onResponse {
+		hook(resp)
+	}
+}
+
+// error runs the error hooks in order.
+func (h *hooks) error(req *http.Request, err error) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.onError {
+		hook(req, err)
+	}
+}
+
+// retry runs the retry hooks in order.
+func (h *hooks) retry(event RetryEvent) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.onRetry {
+		hook(event)
+	}
+}
+
+// clientHooks returns the hooks of c, creating them on first use.
+func (c *Client) clientHooks() *hooks {
+	if c.hooks == nil {
+		c.hooks = &hooks{}
+	}
+	return c.hooks
+}
@@ -0,0 +1,180 @@
+// Package httpc provides tests for lifecycle hooks.
+// This file contains tests for the OnRequest, OnResponse, OnError and
+// OnRetry hooks.
+package httpc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Hooks_Order(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Hook")))
+	}))
+	defer server.Close()
+
+	var calls []string
+	client := NewClient(
+		WithOnRequest(func(req *http.Request) error {
+			calls = append(calls, "request 1")
+			req.Header.Set("X-Hook", "first")
+			return nil
+		}),
+		WithOnRequest(func(req *http.Request) error {
+			calls = append(calls, "request 2")
+			req.Header.Set("X-Hook", req.Header.Get("X-Hook")+",second")
+			return nil
+		}),
+		WithOnResponse(func(resp *Response) {
+			calls = append(calls, "response 1")
+		}),
+		WithOnResponse(func(resp *Response) {
+			calls = append(calls, "response 2")
+		}),
+		WithOnError(func(req *http.Request, err error) {
+			calls = append(calls, "error")
+		}),
+	)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	body, _ := resp.String()
+	if body != "first,second" {
+		t.Errorf("Expected hooks to modify the request in order, server saw %q", body)
+	}
+
+	if got := strings.Join(calls, ", "); got != "request 1, request 2, response 1, response 2" {
+		t.Errorf("Unexpected hook calls: %s", got)
+	}
+}
+
+func TestClient_OnRequest_Abort(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	errDisabled := errors.New("deletes are disabled")
+	var gotErr error
+	var responses, later atomic.Int32
+	client := NewClient(
+		WithOnRequest(func(req *http.Request) error {
+			if req.Method == http.MethodDelete {
+				return errDisabled
+			}
+			return nil
+		}),
+		WithOnRequest(func(req *http.Request) error {
+			later.Add(1)
+			return nil
+		}),
+		WithOnResponse(func(resp *Response) { responses.Add(1) }),
+		WithOnError(func(req *http.Request, err error) { gotErr = err }),
+	)
+
+	_, err := client.Delete(server.URL + "/users/1")
+	if !errors.Is(err, errDisabled) {
+		t.Fatalf("Expected the hook error, got %v", err)
+	}
+	if requests.Load() != 0 {
+		t.Error("Expected the aborted request not to be sent")
+	}
+	if later.Load() != 0 || responses.Load() != 0 {
+		t.Error("Expected no further hooks after an abort")
+	}
+	if !errors.Is(gotErr, errDisabled) {
+		t.Errorf("Expected OnError to receive the hook error, got %v", gotErr)
+	}
+}
+
+func TestClient_OnError(t *testing.T) {
+	var gotReq *http.Request
+	var gotErr error
+	client := NewClient(
+		WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			})
+		}),
+		WithOnError(func(req *http.Request, err error) {
+			gotReq, gotErr = req, err
+		}),
+	)
+
+	if _, err := client.Get("https://api.example.com/users"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if gotReq == nil || gotReq.URL.Path != "/users" {
+		t.Errorf("Expected OnError to receive the request, got %v", gotReq)
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "connection refused") {
+		t.Errorf("Expected OnError to receive the error, got %v", gotErr)
+	}
+}
+
+func TestClient_OnResponse_Body(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"not found"}`))
+	}))
+	defer server.Close()
+
+	var hookBody string
+	client := NewClient(WithOnResponse(func(resp *Response) {
+		hookBody, _ = resp.String()
+	}))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	body, _ := resp.String()
+	if hookBody != `{"error":"not found"}` || body != hookBody {
+		t.Errorf("Expected the body read by the hook to remain available, got %q and %q", hookBody, body)
+	}
+}
+
+func TestClient_OnRetry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var configCalls atomic.Int32
+	var attempts []int
+	client := NewClient(
+		WithRetry(RetryConfig{
+			MaxRetries: 3,
+			Backoff:    time.Millisecond,
+			RetryIf:    defaultRetryCondition,
+			OnRetry:    func(RetryEvent) { configCalls.Add(1) },
+		}),
+		WithOnRetry(func(event RetryEvent) {
+			attempts = append(attempts, event.Attempt)
+		}),
+	)
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("Expected OnRetry for attempts 1 and 2, got %v", attempts)
+	}
+	if configCalls.Load() != 2 {
+		t.Errorf("Expected RetryConfig.OnRetry to still be called, got %d", configCalls.Load())
+	}
+}
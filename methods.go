@@ -229,3 +229,41 @@ func WithContext(ctx context.Context) RequestOption {
 		rb.Context(ctx)
 	}
 }
+
+// WithRequestRetry returns a RequestOption that overrides the client's retry
+// configuration for a single request. See RequestBuilder.Retry.
+//
+// Example:
+//
+//	config := httpc.DefaultRetryConfig()
+//	config.MaxRetries = 10
+//	resp, err := client.Get("/api/report", httpc.WithRequestRetry(*config))
+func WithRequestRetry(config RetryConfig) RequestOption {
+	return func(rb *RequestBuilder) {
+		rb.Retry(config)
+	}
+}
+
+// WithNoRetry returns a RequestOption that disables retries for a single request.
+// See RequestBuilder.NoRetry.
+//
+// Example:
+//
+//	resp, err := client.Post("/api/payments", payment, httpc.WithNoRetry())
+func WithNoRetry() RequestOption {
+	return func(rb *RequestBuilder) {
+		rb.NoRetry()
+	}
+}
+
+// WithPriority returns a RequestOption that sets the priority of the request in
+// the concurrency limiter queue. See RequestBuilder.Priority.
+//
+// Example:
+//
+//	resp, err := client.Get("/api/profile", httpc.WithPriority(httpc.PriorityHigh))
+func WithPriority(priority Priority) RequestOption {
+	return func(rb *RequestBuilder) {
+		rb.Priority(priority)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -419,3 +420,34 @@ func TestClient_GetWithContext_WithOptions(t *testing.T) {
 		t.Fatalf("GetWithContext() with options failed: %v", err)
 	}
 }
+
+func TestClient_Get_WithRequestRetryOptions(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+
+	_, _ = client.Get(server.URL, WithNoRetry())
+	if callCount.Load() != 1 {
+		t.Errorf("WithNoRetry: expected 1 call, got %d", callCount.Load())
+	}
+
+	callCount.Store(0)
+	_, _ = client.Get(server.URL, WithRequestRetry(RetryConfig{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+	if callCount.Load() != 4 {
+		t.Errorf("WithRequestRetry: expected 4 calls, got %d", callCount.Load())
+	}
+}
@@ -0,0 +1,405 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the metrics transport, the MetricsCollector interface it
+// reports to, and an in-memory collector.
+package httpc
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricLabels identifies the series a request is recorded under.
+type MetricLabels struct {
+	// Method is the HTTP method of the request
+	Method string
+
+	// Host is the host of the request URL
+	Host string
+
+	// Route is the first MetricsConfig.Routes template matching the request
+	// path, or empty if none matches, which keeps label cardinality bounded.
+	Route string
+}
+
+// RequestMetrics describes a completed request.
+type RequestMetrics struct {
+	// Labels identifies the series of the request
+	Labels MetricLabels
+
+	// StatusCode is the response status code, or 0 if the request failed
+	StatusCode int
+
+	// StatusClass is "1xx" to "5xx", or "error" if the request failed
+	StatusClass string
+
+	// Duration is the time from sending the request to receiving the response headers
+	Duration time.Duration
+
+	// BytesSent is the size of the request body
+	BytesSent int64
+
+	// BytesReceived is the number of response body bytes read by the caller
+	BytesReceived int64
+
+	// Retries is the number of retries performed by WithRetry for the request
+	Retries int
+
+	// Err is the error of a failed request
+	Err error
+}
+
+// MetricsCollector receives the measurements of the metrics transport. It lets
+// applications forward metrics to a monitoring system, such as a Prometheus
+// registry, without httpc depending on it. Implementations must be safe for
+// concurrent use.
+//
+// Example:
+//
+//	type promCollector struct {
+//		inFlight *prometheus.GaugeVec
+//		latency  *prometheus.HistogramVec
+//	}
+//
+//	func (c *promCollector) AddInFlight(l httpc.MetricLabels, delta int) {
+//		c.inFlight.WithLabelValues(l.Method, l.Host, l.Route).Add(float64(delta))
+//	}
+//
+//	func (c *promCollector) ObserveRequest(m httpc.RequestMetrics) {
+//		l := m.Labels
+//		c.latency.WithLabelValues(l.Method, l.Host, l.Route, m.StatusClass).Observe(m.Duration.Seconds())
+//	}
+type MetricsCollector interface {
+	// AddInFlight is called with +1 when a request starts and -1 when it completes.
+	AddInFlight(labels MetricLabels, delta int)
+
+	// ObserveRequest is called once per request, when it completes.
+	ObserveRequest(metrics RequestMetrics)
+}
+
+// MetricsConfig configures the metrics transport.
+type MetricsConfig struct {
+	// Collector receives the measurements. It is required.
+	Collector MetricsCollector
+
+	// Routes are path templates used for the Route label, in the format of
+	// KeyByPathTemplate: segments written as {name} match any single segment.
+	Routes []string
+}
+
+// metricsTransport is an http.RoundTripper that reports request metrics to a
+// MetricsCollector.
+type metricsTransport struct {
+	transport http.RoundTripper
+	config    MetricsConfig
+}
+
+// RoundTrip implements http.RoundTripper by measuring the request. A request
+// with a response body completes when the body is closed or fully read, so
+// that BytesReceived and the in-flight gauge cover the whole exchange.
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	labels := t.labels(req)
+	collector := t.config.Collector
+
+	// Bodies of unknown length are counted as they are sent
+	var sent *countingReader
+	if req.Body != nil && req.Body != http.NoBody && req.ContentLength <= 0 {
+		sent = &countingReader{ReadCloser: req.Body}
+		req = req.Clone(req.Context())
+		req.Body = sent
+	}
+
+	collector.AddInFlight(labels, 1)
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+
+	metrics := RequestMetrics{
+		Labels:   labels,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if req.ContentLength > 0 {
+		metrics.BytesSent = req.ContentLength
+	}
+
+	// complete reports the request once the body is done with.
+	complete := func(received int64) {
+		if sent != nil {
+			metrics.BytesSent = sent.n.Load()
+		}
+		metrics.BytesReceived = received
+		_, errs := attemptRecorderFrom(req.Context()).snapshot()
+		metrics.Retries = len(errs)
+
+		collector.AddInFlight(labels, -1)
+		collector.ObserveRequest(metrics)
+	}
+
+	if err != nil {
+		metrics.StatusClass = "error"
+		complete(0)
+		return nil, err
+	}
+
+	metrics.StatusCode = resp.StatusCode
+	metrics.StatusClass = statusClass(resp.StatusCode)
+
+	if resp.Body == nil {
+		complete(0)
+		return resp, nil
+	}
+
+	resp.Body = &metricsBody{ReadCloser: resp.Body, complete: complete}
+	return resp, nil
+}
+
+// labels returns the labels of req.
+func (t *metricsTransport) labels(req *http.Request) MetricLabels {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	route, _ := matchPathTemplate(req.URL.Path, t.config.Routes)
+	return MetricLabels{Method: method, Host: req.URL.Host, Route: route}
+}
+
+// statusClass returns the class of an HTTP status code, such as "2xx".
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "error"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// countingReader counts the bytes read from a request body. The count is read
+// from another goroutine, so it is kept atomically.
+type countingReader struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+// Read reads from the underlying body and counts the bytes read.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// metricsBody counts the bytes read from a response body and calls complete
+// once, when the body is closed or fully read.
+type metricsBody struct {
+	io.ReadCloser
+	complete func(received int64)
+	received int64
+	once     sync.Once
+}
+
+// Read reads from the underlying body, counting bytes and completing at EOF.
+func (b *metricsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.received += int64(n)
+	if err == io.EOF {
+		b.once.Do(func() { b.complete(b.received) })
+	}
+	return n, err
+}
+
+// Close closes the underlying body and completes the request.
+func (b *metricsBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.complete(b.received) })
+	return err
+}
+
+// DefaultLatencyBuckets are the latency histogram bucket bounds used by
+// NewMemoryCollector when none are given.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// MetricSeries holds the aggregated metrics of one label set.
+type MetricSeries struct {
+	// Labels identifies the series
+	Labels MetricLabels
+
+	// Requests is the number of completed requests
+	Requests int64
+
+	// StatusClasses counts completed requests by status class ("2xx", "error", ...)
+	StatusClasses map[string]int64
+
+	// LatencyBuckets counts requests by duration: LatencyBuckets[i] is the number
+	// of requests that took at most Buckets()[i], and the last element counts
+	// all requests (the +Inf bucket). Counts are cumulative.
+	LatencyBuckets []int64
+
+	// LatencySum is the total duration of all requests
+	LatencySum time.Duration
+
+	// BytesSent is the total size of request bodies
+	BytesSent int64
+
+	// BytesReceived is the total number of response body bytes read
+	BytesReceived int64
+
+	// Retries is the total number of retries
+	Retries int64
+
+	// InFlight is the number of requests currently in flight
+	InFlight int64
+}
+
+// MemoryCollector is a MetricsCollector that aggregates metrics in memory.
+// It is intended for tests and debugging. It is safe for concurrent use.
+//
+// Example:
+//
+//	collector := httpc.NewMemoryCollector()
+//	client := httpc.NewClient(httpc.WithMetrics(httpc.MetricsConfig{Collector: collector}))
+//	// ...
+//	for _, series := range collector.Snapshot() {
+//		fmt.Println(series.Labels.Host, series.Requests, series.StatusClasses["5xx"])
+//	}
+type MemoryCollector struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	series  map[MetricLabels]*MetricSeries
+}
+
+// NewMemoryCollector creates a MemoryCollector with the given latency bucket
+// bounds, in increasing order. If none are given, DefaultLatencyBuckets is used.
+func NewMemoryCollector(buckets ...time.Duration) *MemoryCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	return &MemoryCollector{
+		buckets: buckets,
+		series:  make(map[MetricLabels]*MetricSeries),
+	}
+}
+
+// Buckets returns the latency bucket bounds.
+func (c *MemoryCollector) Buckets() []time.Duration {
+	return append([]time.Duration(nil), c.buckets...)
+}
+
+// get returns the series for labels, creating it on first use.
+// The caller must hold c.mu.
+func (c *MemoryCollector) get(labels MetricLabels) *MetricSeries {
+	s, ok := c.series[labels]
+	if !ok {
+		s = &MetricSeries{
+			Labels:         labels,
+			StatusClasses:  make(map[string]int64),
+			LatencyBuckets: make([]int64, len(c.buckets)+1),
+		}
+		c.series[labels] = s
+	}
+	return s
+}
+
+// AddInFlight implements MetricsCollector.
+func (c *MemoryCollector) AddInFlight(labels MetricLabels, delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.get(labels).InFlight += int64(delta)
+}
+
+// ObserveRequest implements MetricsCollector.
+func (c *MemoryCollector) ObserveRequest(m RequestMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.get(m.Labels)
+	s.Requests++
+	s.StatusClasses[m.StatusClass]++
+	s.LatencySum += m.Duration
+	s.BytesSent += m.BytesSent
+	s.BytesReceived += m.BytesReceived
+	s.Retries += int64(m.Retries)
+
+	for i, bound := range c.buckets {
+		if m.Duration <= bound {
+			s.LatencyBuckets[i]++
+		}
+	}
+	s.LatencyBuckets[len(c.buckets)]++
+}
+
+// Series returns a copy of the series for labels. A series that has not been
+// recorded is returned empty.
+func (c *MemoryCollector) Series(labels MetricLabels) MetricSeries {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.series[labels]
+	if !ok {
+		return MetricSeries{
+			Labels:         labels,
+			StatusClasses:  map[string]int64{},
+			LatencyBuckets: make([]int64, len(c.buckets)+1),
+		}
+	}
+	return copySeries(s)
+}
+
+// Snapshot returns a copy of every recorded series, sorted by labels.
+func (c *MemoryCollector) Snapshot() []MetricSeries {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := make([]MetricSeries, 0, len(c.series))
+	for _, s := range c.series {
+		snapshot = append(snapshot, copySeries(s))
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		a, b := snapshot[i].Labels, snapshot[j].Labels
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		return a.Method < b.Method
+	})
+	return snapshot
+}
+
+// Reset discards all recorded series.
+func (c *MemoryCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.series = make(map[MetricLabels]*MetricSeries)
+}
+
+// copySeries returns a deep copy of s.
+func copySeries(s *MetricSeries) MetricSeries {
+	out := *s
+	out.StatusClasses = make(map[string]int64, len(s.StatusClasses))
+	for class, n := range s.StatusClasses {
+		out.StatusClasses[class] = n
+	}
+	out.LatencyBuckets = append([]int64(nil), s.LatencyBuckets...)
+	return out
+}
@@ -0,0 +1,210 @@
+// Package httpc provides tests for the metrics transport.
+// This file contains tests for labels, status classes, byte counts, retries,
+// in-flight gauges, and the in-memory collector.
+package httpc
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WithMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	collector := NewMemoryCollector()
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMetrics(MetricsConfig{Collector: collector, Routes: []string{"/users/{id}"}}),
+	)
+
+	for _, id := range []string{"1", "2"} {
+		resp, err := client.Get("/users/" + id)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		_, _ = resp.Bytes()
+	}
+
+	// A streamed body of unknown length is counted as it is sent
+	body := io.NopCloser(strings.NewReader("payload"))
+	resp, err := client.NewRequest().Method(http.MethodPost).URL("/missing").Body(body).Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	users := collector.Series(MetricLabels{Method: http.MethodGet, Host: host, Route: "/users/{id}"})
+	if users.Requests != 2 {
+		t.Errorf("Expected 2 requests for the route template, got %d", users.Requests)
+	}
+	if users.StatusClasses["2xx"] != 2 {
+		t.Errorf("Expected 2 2xx responses, got %v", users.StatusClasses)
+	}
+	if users.BytesReceived != 10 {
+		t.Errorf("Expected 10 bytes received, got %d", users.BytesReceived)
+	}
+	if users.InFlight != 0 {
+		t.Errorf("Expected no requests in flight, got %d", users.InFlight)
+	}
+	if last := users.LatencyBuckets[len(users.LatencyBuckets)-1]; last != 2 {
+		t.Errorf("Expected 2 requests in the +Inf bucket, got %d", last)
+	}
+
+	missing := collector.Series(MetricLabels{Method: http.MethodPost, Host: host})
+	if missing.StatusClasses["4xx"] != 1 {
+		t.Errorf("Expected a 4xx response for an unmatched route, got %v", missing.StatusClasses)
+	}
+	if missing.BytesSent != int64(len("payload")) {
+		t.Errorf("Expected %d bytes sent, got %d", len("payload"), missing.BytesSent)
+	}
+
+	if n := len(collector.Snapshot()); n != 2 {
+		t.Errorf("Expected 2 series, got %d", n)
+	}
+}
+
+func TestClient_WithMetrics_Retries(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := DefaultRetryConfig()
+	config.Backoff = time.Millisecond
+
+	collector := NewMemoryCollector()
+	client := NewClient(
+		WithRetry(*config),
+		WithMetrics(MetricsConfig{Collector: collector}),
+	)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	snapshot := collector.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("Expected 1 series, got %d", len(snapshot))
+	}
+	if snapshot[0].Requests != 1 || snapshot[0].Retries != 2 {
+		t.Errorf("Expected 1 request with 2 retries, got %d requests and %d retries",
+			snapshot[0].Requests, snapshot[0].Retries)
+	}
+}
+
+func TestClient_WithMetrics_InFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	collector := NewMemoryCollector()
+	client := NewClient(WithMetrics(MetricsConfig{Collector: collector}))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	labels := collector.Snapshot()[0].Labels
+	if got := collector.Series(labels).InFlight; got != 1 {
+		t.Errorf("Expected 1 request in flight until the body is read, got %d", got)
+	}
+
+	_, _ = resp.Bytes()
+
+	series := collector.Series(labels)
+	if series.InFlight != 0 || series.Requests != 1 {
+		t.Errorf("Expected the request to complete, got %d in flight and %d requests", series.InFlight, series.Requests)
+	}
+}
+
+func TestMetricsTransport_Error(t *testing.T) {
+	collector := NewMemoryCollector()
+	transport := &metricsTransport{
+		transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}),
+		config: MetricsConfig{Collector: collector},
+	}
+
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "http", Host: "api.example.com", Path: "/"}}
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	series := collector.Series(MetricLabels{Method: http.MethodGet, Host: "api.example.com"})
+	if series.StatusClasses["error"] != 1 || series.InFlight != 0 {
+		t.Errorf("Expected 1 error and nothing in flight, got %v and %d", series.StatusClasses, series.InFlight)
+	}
+}
+
+func TestMemoryCollector_Buckets(t *testing.T) {
+	collector := NewMemoryCollector(100*time.Millisecond, 10*time.Millisecond)
+	labels := MetricLabels{Method: http.MethodGet, Host: "a"}
+
+	for _, d := range []time.Duration{5 * time.Millisecond, 50 * time.Millisecond, time.Second} {
+		collector.ObserveRequest(RequestMetrics{Labels: labels, Duration: d, StatusClass: "2xx"})
+	}
+
+	buckets := collector.Buckets()
+	if buckets[0] != 10*time.Millisecond || buckets[1] != 100*time.Millisecond {
+		t.Fatalf("Expected sorted buckets, got %v", buckets)
+	}
+
+	expected := []int64{1, 2, 3}
+	got := collector.Series(labels).LatencyBuckets
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected cumulative buckets %v, got %v", expected, got)
+		}
+	}
+
+	collector.Reset()
+	if n := len(collector.Snapshot()); n != 0 {
+		t.Errorf("Expected no series after Reset, got %d", n)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := map[int]string{
+		101: "1xx",
+		200: "2xx",
+		304: "3xx",
+		429: "4xx",
+		503: "5xx",
+		0:   "error",
+		700: "error",
+	}
+
+	for code, expected := range tests {
+		if got := statusClass(code); got != expected {
+			t.Errorf("statusClass(%d) = %q, expected %q", code, got, expected)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package httpc
 
 import (
-	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -162,19 +161,38 @@ func WithApiKey(headerName, apiKey string) Option {
 	return WithHeader(headerName, apiKey)
 }
 
-// WithRequestId generates and sets a unique request ID header for tracing.
+// WithRequestId sets a unique request ID header on each request for tracing.
 // If headerName is empty, defaults to "X-Request-Id".
-// A new request ID is generated for each client instance.
+// IDs are random UUIDs (see NewUUIDv4); use WithRequestIdGenerator for
+// another format. A request that already has the header, or whose context
+// carries an ID set with ContextWithRequestID, keeps that ID. The ID is the
+// same for all retries of a request, is available from Response.RequestID,
+// and is included in the lines of the logging transports.
 //
 // Example:
 //
 //	client := httpc.NewClient(httpc.WithRequestId("X-Request-ID"))
 func WithRequestId(headerName string) Option {
+	return WithRequestIdGenerator(headerName, NewUUIDv4)
+}
+
+// WithRequestIdGenerator is like WithRequestId, but generates IDs with
+// generator, such as NewUUIDv7 or NewULID for IDs that sort by time.
+// If generator is nil, NewUUIDv4 is used.
+//
+// Example:
+//
+//	client := httpc.NewClient(httpc.WithRequestIdGenerator("X-Request-Id", httpc.NewULID))
+func WithRequestIdGenerator(headerName string, generator RequestIDGenerator) Option {
 	if headerName == "" {
 		headerName = "X-Request-Id"
 	}
-	requestID := fmt.Sprintf("req-%d", time.Now().UnixNano())
-	return WithHeader(headerName, requestID)
+	if generator == nil {
+		generator = NewUUIDv4
+	}
+	return func(c *Client) {
+		c.requestID = &requestIDConfig{header: headerName, generator: generator}
+	}
 }
 
 // WithBlockedList configures a list of domains that should be blocked.
@@ -203,8 +221,8 @@ func WithBlockedList(blockedList []string) Option {
 	})
 }
 
-// WithRetry configures automatic retry logic with exponential backoff.
-// Use RetryConfig to specify max retries, backoff duration, and retry conditions.
+// WithRetry configures automatic retry logic with backoff between attempts.
+// Use RetryConfig to specify max retries, backoff schedule, and retry conditions.
 //
 // Example with default retry condition:
 //
@@ -241,13 +259,287 @@ func WithBlockedList(blockedList []string) Option {
 //		RetryIf:    customRetryCondition,
 //	}
 //	client := httpc.NewClient(httpc.WithRetry(config))
+//
+// When config.Budget is set, a single retry budget is created for the client
+// and shared by all of its requests.
 func WithRetry(config RetryConfig) Option {
-	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+	intercept := WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		var budget *retryBudget
+		if config.Budget != nil {
+			budget = newRetryBudget(*config.Budget)
+		}
+
 		return &retryTransport{
 			transport: rt,
 			config:    config,
+			budget:    budget,
 		}
 	})
+	return func(c *Client) {
+		c.retry = true
+		intercept(c)
+	}
+}
+
+// WithHedging enables hedged requests to reduce tail latency.
+// If a request has not completed within config.Delay, another copy is sent,
+// up to config.MaxHedges extra copies; the first successful response is returned
+// and the other copies are cancelled. By default only GET and HEAD requests are
+// hedged. config.MaxInFlight caps the extra copies in flight across the client.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithHedging(httpc.HedgeConfig{
+//			Delay:       50 * time.Millisecond,
+//			MaxHedges:   2,
+//			MaxInFlight: 20,
+//		}),
+//	)
+func WithHedging(config HedgeConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newHedgeTransport(rt, config)
+	})
+}
+
+// WithRateLimit limits the rate of requests sent by the client using a token bucket.
+// Requests wait for a token while honoring their context, or fail with
+// ErrRateLimited when config.FailFast is set. A zero or negative
+// config.RequestsPerSecond disables limiting.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithRateLimit(httpc.RateLimitConfig{
+//			RequestsPerSecond: 10,
+//			Burst:             20,
+//		}),
+//	)
+func WithRateLimit(config RateLimitConfig) Option {
+	if config.RequestsPerSecond <= 0 {
+		return func(*Client) {}
+	}
+
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return &rateLimitTransport{
+			transport: rt,
+			bucket:    newTokenBucket(config.RequestsPerSecond, config.Burst),
+			failFast:  config.FailFast,
+		}
+	})
+}
+
+// WithKeyedRateLimit limits requests with an independent token bucket per
+// request key, such as the host, a path template, or a tenant header.
+// Limiters of keys that stay idle for config.IdleTimeout are evicted.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithKeyedRateLimit(httpc.KeyedRateLimitConfig{
+//			Key:     httpc.KeyByHost(),
+//			Default: httpc.RateLimitConfig{RequestsPerSecond: 10, Burst: 10},
+//			Limits: map[string]httpc.RateLimitConfig{
+//				"api.partner-a.com": {RequestsPerSecond: 2, Burst: 1},
+//			},
+//		}),
+//	)
+func WithKeyedRateLimit(config KeyedRateLimitConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newKeyedRateLimitTransport(rt, config)
+	})
+}
+
+// WithAdaptiveRateLimit paces requests according to the quota the server
+// advertises in X-RateLimit-* or IETF RateLimit headers. Once a response reports
+// the remaining quota and its reset time, later requests to the same key are
+// spread evenly until the reset, and wait for it when the quota is exhausted,
+// instead of running into 429 responses.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithAdaptiveRateLimit(httpc.AdaptiveRateLimitConfig{
+//			MaxWait: 30 * time.Second,
+//		}),
+//	)
+func WithAdaptiveRateLimit(config AdaptiveRateLimitConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newAdaptiveRateLimitTransport(rt, config)
+	})
+}
+
+// WithMaxConcurrency caps the number of requests the client has in flight,
+// optionally per host, and queues the excess. Queued requests start in order
+// of priority (see RequestBuilder.Priority), then arrival, and give up when
+// their context is done. A request holds its slot until its response body is
+// closed or fully read.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithMaxConcurrency(httpc.ConcurrencyConfig{
+//			MaxInFlight:        50,
+//			MaxInFlightPerHost: 10,
+//			MaxQueue:           500,
+//		}),
+//	)
+func WithMaxConcurrency(config ConcurrencyConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newConcurrencyTransport(rt, config)
+	})
+}
+
+// WithCircuitBreaker stops sending requests to a host that keeps failing.
+// Each host has its own circuit: once the failure rate over config.Window
+// reaches config.FailureThreshold, requests to that host fail immediately with
+// a *CircuitOpenError (matching ErrCircuitOpen) until config.OpenTimeout has
+// passed and trial requests show the host has recovered.
+//
+// Add it before WithRetry so that every attempt is counted. The default retry
+// condition does not retry requests rejected by an open circuit.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithCircuitBreaker(httpc.CircuitBreakerConfig{
+//			FailureThreshold: 0.5,
+//			MinRequests:      20,
+//			OpenTimeout:      30 * time.Second,
+//			OnStateChange: func(host string, from, to httpc.CircuitState) {
+//				log.Printf("circuit for %s: %s -> %s", host, from, to)
+//			},
+//		}),
+//		httpc.WithRetry(*httpc.DefaultRetryConfig()),
+//	)
+func WithCircuitBreaker(config CircuitBreakerConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newCircuitBreakerTransport(rt, config)
+	})
+}
+
+// WithMetrics records request counts, latencies, status classes, bytes sent and
+// received, retries, and in-flight requests, labeled by method, host and route
+// template, and reports them to config.Collector. Use NewMemoryCollector in
+// tests, or implement MetricsCollector to forward to a monitoring system.
+//
+// Add it after WithRetry so that each request is recorded once, together with
+// its number of retries.
+//
+// Example:
+//
+//	collector := httpc.NewMemoryCollector()
+//	client := httpc.NewClient(
+//		httpc.WithRetry(*httpc.DefaultRetryConfig()),
+//		httpc.WithMetrics(httpc.MetricsConfig{
+//			Collector: collector,
+//			Routes:    []string{"/users/{id}", "/orders/{id}/items"},
+//		}),
+//	)
+func WithMetrics(config MetricsConfig) Option {
+	if config.Collector == nil {
+		return func(*Client) {}
+	}
+
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return &metricsTransport{transport: rt, config: config}
+	})
+}
+
+// WithTimings enables per-phase timings for every request, exposed through
+// Response.Timings. It uses net/http/httptrace to measure DNS lookup, TCP
+// connect, TLS handshake, time to first byte and body read, which helps tell
+// slow networks from slow servers.
+//
+// Add it before WithRetry or WithHedging so that each attempt is measured on
+// its own and the timings describe the attempt that produced the response.
+//
+// Example:
+//
+//	client := httpc.NewClient(httpc.WithTimings())
+//	resp, _ := client.Get("/api/users")
+//	_, _ = resp.Bytes()
+//	log.Printf("ttfb=%v reused=%v", resp.Timings().TimeToFirstByte, resp.Timings().ConnReused)
+func WithTimings() Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return &timingTransport{transport: rt}
+	})
+}
+
+// WithTracing propagates W3C Trace Context and records a client span for
+// every attempt of a request. The span context stored in the request context
+// with ContextWithSpanContext is used as the parent; without one, a new trace
+// is started for the request and shared by all of its attempts. The
+// traceparent and tracestate headers (and the B3 headers if config.B3 is set)
+// of the attempt's span are injected, and the span is passed to
+// config.Exporter once the response headers are received.
+//
+// Add it before WithRetry so that every attempt, including retries, gets its
+// own span.
+//
+// Example:
+//
+//	exporter := httpc.NewInMemoryExporter()
+//	client := httpc.NewClient(
+//		httpc.WithTracing(httpc.TracingConfig{Exporter: exporter}),
+//		httpc.WithRetry(*httpc.DefaultRetryConfig()),
+//	)
+//
+//	ctx := httpc.ContextWithSpanContext(context.Background(), parent)
+//	resp, err := client.NewRequest().Context(ctx).URL("/users").Do()
+func WithTracing(config TracingConfig) Option {
+	intercept := WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return &tracingTransport{transport: rt, config: config}
+	})
+	return func(c *Client) {
+		c.tracing = true
+		intercept(c)
+	}
+}
+
+// WithCache adds a private HTTP cache (RFC 9111) for GET requests. Responses
+// are fresh for their Cache-Control max-age, their Expires date, or, without
+// either, 10% of the time since their Last-Modified date (up to 24 hours).
+// Fresh responses are served from config.Storage without contacting the server;
+// stale ones are revalidated with If-None-Match or If-Modified-Since. Vary,
+// no-store, no-cache, must-revalidate and the request directives max-age,
+// min-fresh, max-stale and only-if-cached are honored. Successful POST, PUT,
+// PATCH and DELETE requests invalidate the stored responses of their URL.
+// Response.FromCache reports cache hits.
+//
+// Add it after WithRetry and the other interceptors, so that cache hits skip them.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithRetry(*httpc.DefaultRetryConfig()),
+//		httpc.WithCache(httpc.CacheConfig{Storage: httpc.NewLRUCache(500)}),
+//	)
+func WithCache(config CacheConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newCacheTransport(rt, config)
+	})
+}
+
+// WithRevalidation remembers the last 200 response to each GET URL that
+// carried an ETag or Last-Modified header, and sends its validators as
+// If-None-Match or If-Modified-Since on the next GET to that URL. A 304 Not
+// Modified answer is transparently replaced by the remembered response, so
+// that Response.Bytes and Response.JSON see the full body; Response.Revalidated
+// reports it. Unlike WithCache, every request reaches the server, whatever the
+// Cache-Control headers.
+//
+// Example:
+//
+//	client := httpc.NewClient(httpc.WithRevalidation(httpc.RevalidationConfig{}))
+//
+//	resp, err := client.Get("/api/countries") // 304 served as the previous 200
+//	var countries []Country
+//	err = resp.JSON(&countries)
+func WithRevalidation(config RevalidationConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newRevalidationTransport(rt, config)
+	})
 }
 
 // WithLogger configures request/response logging using the provided logger.
@@ -266,6 +558,91 @@ func WithLogger(logger *log.Logger) Option {
 	})
 }
 
+// WithSlog logs every request as a structured log/slog record with its
+// method, URL, status, duration, attempt count, request ID and sizes.
+// Transport errors and 5xx responses are logged at config.ErrorLevel; other
+// requests are logged at config.Level and can be sampled with config.SampleRate.
+//
+// Add it after WithRetry so that each request is logged once with its number
+// of attempts.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithRetry(*httpc.DefaultRetryConfig()),
+//		httpc.WithSlog(httpc.SlogConfig{
+//			Logger:     slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+//			Level:      slog.LevelDebug,
+//			SampleRate: 0.1, // log 10% of successful requests
+//		}),
+//	)
+func WithSlog(config SlogConfig) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return newSlogTransport(rt, config)
+	})
+}
+
+// WithRedaction sets the redaction policy applied by all logging transports of
+// the client (WithLogger, WithSlog and WithDebug), masking the configured
+// headers, query parameters and JSON or form body fields in addition to those
+// of DefaultRedactionPolicy (see RedactionPolicy.WithDefaults). Without it,
+// DefaultRedactionPolicy is used.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithDebug(),
+//		httpc.WithRedaction(httpc.RedactionPolicy{
+//			QueryParams: []string{"signature"},
+//			BodyFields:  []string{"user.ssn"},
+//		}),
+//	)
+func WithRedaction(policy RedactionPolicy) Option {
+	return func(c *Client) {
+		c.redaction = policy.WithDefaults()
+	}
+}
+
+// WithHAR records every exchange of the client into recorder, which writes an
+// HTTP Archive (HAR 1.2) file that can be opened in browser developer tools.
+// Headers, bounded bodies and timings are captured, with the same redaction
+// as DebugTransport. Failed exchanges are recorded with their error.
+//
+// Example:
+//
+//	recorder := httpc.NewHARRecorder("partner.har", httpc.HARConfig{MaxBodySize: 64 << 10})
+//	defer recorder.Close()
+//
+//	client := httpc.NewClient(httpc.WithHAR(recorder))
+func WithHAR(recorder *HARRecorder) Option {
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return &harTransport{transport: rt, recorder: recorder}
+	})
+}
+
+// WithCurlOnError logs every failed request (a transport error or a 4xx or 5xx
+// response) as a curl command line, so that it can be reproduced outside the
+// application. Sensitive values are masked according to the Client redaction
+// policy (see WithRedaction). If logger is nil, log.Default() is used.
+//
+// Add it before interceptors that set headers, such as WithAuthorization, for
+// the logged command to include them.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithCurlOnError(log.New(os.Stderr, "[HTTP] ", log.LstdFlags)),
+//		httpc.WithAuthorization("token"),
+//	)
+func WithCurlOnError(logger *log.Logger) Option {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+		return &curlOnErrorTransport{transport: rt, logger: logger}
+	})
+}
+
 // WithDebug enables debug logging for the client.
 // When enabled, the client will log detailed information about requests and responses.
 //
@@ -278,6 +655,79 @@ func WithDebug() Option {
 	})
 }
 
+// WithOnRequest registers a hook called with every request built by
+// RequestBuilder before it is sent. Hooks run in registration order and may
+// modify the request; the first hook returning an error aborts the request
+// with that error.
+//
+// Example:
+//
+//	client := httpc.NewClient(httpc.WithOnRequest(func(req *http.Request) error {
+//		if req.Method == http.MethodDelete && !allowDeletes {
+//			return errors.New("deletes are disabled")
+//		}
+//		req.Header.Set("X-Tenant", tenant)
+//		return nil
+//	}))
+func WithOnRequest(hook RequestHook) Option {
+	return func(c *Client) {
+		h := c.clientHooks()
+		h.onRequest = append(h.onRequest, hook)
+	}
+}
+
+// WithOnResponse registers a hook called with the Response of every request
+// that received one, whatever its status. Hooks run in registration order.
+// A hook reading the body (for example with Bytes) caches it for the caller.
+//
+// Example:
+//
+//	client := httpc.NewClient(httpc.WithOnResponse(func(resp *httpc.Response) {
+//		log.Printf("%s %s: %d after %d attempts",
+//			resp.Request.Method, resp.Request.URL, resp.StatusCode, resp.Attempts())
+//	}))
+func WithOnResponse(hook ResponseHook) Option {
+	return func(c *Client) {
+		h := c.clientHooks()
+		h.onResponse = append(h.onResponse, hook)
+	}
+}
+
+// WithOnError registers a hook called with the request and error of every
+// request that failed without a response, including requests aborted by a
+// WithOnRequest hook. Hooks run in registration order.
+//
+// Example:
+//
+//	client := httpc.NewClient(httpc.WithOnError(func(req *http.Request, err error) {
+//		log.Printf("%s %s failed: %v", req.Method, req.URL, err)
+//	}))
+func WithOnError(hook ErrorHook) Option {
+	return func(c *Client) {
+		h := c.clientHooks()
+		h.onError = append(h.onError, hook)
+	}
+}
+
+// WithOnRetry registers a hook called before each retry of a request, after
+// RetryConfig.OnRetry. Hooks run in registration order and only fire on
+// clients configured with WithRetry.
+//
+// Example:
+//
+//	client := httpc.NewClient(
+//		httpc.WithRetry(*httpc.DefaultRetryConfig()),
+//		httpc.WithOnRetry(func(e httpc.RetryEvent) {
+//			log.Printf("retry %d in %v: %v", e.Attempt, e.Delay, e.Err)
+//		}),
+//	)
+func WithOnRetry(hook RetryHook) Option {
+	return func(c *Client) {
+		h := c.clientHooks()
+		h.onRetry = append(h.onRetry, hook)
+	}
+}
+
 // WithInterceptor adds a request interceptor to the client.
 // Interceptors are executed in the order they are added and can modify
 // requests before they are sent or return errors to prevent execution.
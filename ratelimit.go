@@ -0,0 +1,368 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the token-bucket rate limiting transports, both a single
+// client-wide limiter and independent limiters keyed by request attributes.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a request is rejected by a client-side rate
+// limiter, either because the limiter is configured to fail fast or because
+// the wait for a token would outlast the request context deadline.
+//
+// Example:
+//
+//	_, err := client.Get("/api/users")
+//	if errors.Is(err, httpc.ErrRateLimited) {
+//		log.Println("slow down")
+//	}
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitConfig configures a token-bucket rate limiter.
+// Tokens are added at RequestsPerSecond up to Burst; each request consumes one.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained request rate. Zero or less disables limiting.
+	RequestsPerSecond float64
+
+	// Burst is the maximum number of requests allowed at once.
+	// If zero, a burst of 1 is used.
+	Burst int
+
+	// FailFast makes requests fail with ErrRateLimited when no token is available,
+	// instead of waiting for one.
+	FailFast bool
+}
+
+// tokenBucket is a token-bucket rate limiter. It is safe for concurrent use.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newTokenBucket creates a full bucket refilled at rate tokens per second.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// advance refills the bucket for the time elapsed since the last update.
+// The caller must hold b.mu.
+func (b *tokenBucket) advance() {
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// allow takes a token if one is available right now.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// reserve takes a token, possibly going into debt, and returns how long the
+// caller must wait before the token is actually available.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// unreserve returns a token taken by reserve that will not be used.
+func (b *tokenBucket) unreserve() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// wait blocks until a token is available or ctx is done.
+// It fails immediately with ErrRateLimited if the wait would outlast the ctx deadline.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		b.unreserve()
+		return fmt.Errorf("%w: waiting %v would exceed the request deadline", ErrRateLimited, delay)
+	}
+
+	if err := sleepContext(ctx, delay); err != nil {
+		b.unreserve()
+		return err
+	}
+	return nil
+}
+
+// take acquires a token according to the configured mode: it either waits
+// for one or fails fast with ErrRateLimited.
+func (b *tokenBucket) take(ctx context.Context, failFast bool) error {
+	if failFast {
+		if !b.allow() {
+			return ErrRateLimited
+		}
+		return nil
+	}
+	return b.wait(ctx)
+}
+
+// rateLimitTransport is an http.RoundTripper that limits the rate of outgoing
+// requests with a token bucket shared by all requests of a Client.
+type rateLimitTransport struct {
+	transport http.RoundTripper
+	bucket    *tokenBucket
+	failFast  bool
+}
+
+// RoundTrip implements http.RoundTripper by waiting for a token (or failing fast)
+// before delegating to the wrapped transport.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.bucket.take(req.Context(), t.failFast); err != nil {
+		return nil, err
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// RateLimitKeyFunc derives the limiter key for a request. Requests with the
+// same key share a limiter, including requests with an empty key.
+type RateLimitKeyFunc func(*http.Request) string
+
+// KeyByHost returns a RateLimitKeyFunc that keys limiters by request host.
+//
+// Example:
+//
+//	httpc.KeyedRateLimitConfig{Key: httpc.KeyByHost()}
+func KeyByHost() RateLimitKeyFunc {
+	return func(req *http.Request) string {
+		return req.URL.Host
+	}
+}
+
+// KeyByHeader returns a RateLimitKeyFunc that keys limiters by the value of
+// a request header, such as a tenant ID.
+//
+// Example:
+//
+//	httpc.KeyedRateLimitConfig{Key: httpc.KeyByHeader("X-Tenant-Id")}
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(req *http.Request) string {
+		return req.Header.Get(name)
+	}
+}
+
+// KeyByPathTemplate returns a RateLimitKeyFunc that keys limiters by host and
+// the first matching path template. Template segments written as {name} match
+// any single path segment, so "/users/{id}" matches "/users/42".
+// Requests matching no template are keyed by host alone.
+//
+// Example:
+//
+//	httpc.KeyedRateLimitConfig{
+//		Key: httpc.KeyByPathTemplate("/users/{id}", "/orders/{id}/items"),
+//	}
+func KeyByPathTemplate(templates ...string) RateLimitKeyFunc {
+	return func(req *http.Request) string {
+		if template, ok := matchPathTemplate(req.URL.Path, templates); ok {
+			return req.URL.Host + template
+		}
+		return req.URL.Host
+	}
+}
+
+// matchPathTemplate returns the first template matching path.
+func matchPathTemplate(path string, templates []string) (string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for _, template := range templates {
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+
+		matched := true
+		for i, part := range parts {
+			isParam := strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}")
+			if !isParam && part != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return template, true
+		}
+	}
+
+	return "", false
+}
+
+// KeyedRateLimitConfig configures independent rate limiters per request key,
+// for example one per partner API host or per tenant.
+type KeyedRateLimitConfig struct {
+	// Key derives the limiter key from a request. See KeyByHost, KeyByHeader
+	// and KeyByPathTemplate. If nil, KeyByHost is used.
+	Key RateLimitKeyFunc
+
+	// Default is the limit applied to keys without an entry in Limits.
+	Default RateLimitConfig
+
+	// Limits holds per-key limits that override Default.
+	Limits map[string]RateLimitConfig
+
+	// IdleTimeout evicts the limiter of a key that has not been used for this
+	// long, bounding memory for high-cardinality keys. If zero, 10 minutes is used.
+	IdleTimeout time.Duration
+}
+
+// defaultRateLimitIdleTimeout is used when KeyedRateLimitConfig.IdleTimeout is not set.
+const defaultRateLimitIdleTimeout = 10 * time.Minute
+
+// keyedLimiter is the limiter state of a single key.
+type keyedLimiter struct {
+	bucket   *tokenBucket
+	failFast bool
+	lastUsed time.Time
+	active   int
+}
+
+// keyedRateLimitTransport is an http.RoundTripper that maintains an independent
+// token bucket per request key and evicts idle ones.
+type keyedRateLimitTransport struct {
+	transport http.RoundTripper
+	config    KeyedRateLimitConfig
+
+	mu        sync.Mutex
+	limiters  map[string]*keyedLimiter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// newKeyedRateLimitTransport creates a keyedRateLimitTransport.
+func newKeyedRateLimitTransport(rt http.RoundTripper, config KeyedRateLimitConfig) *keyedRateLimitTransport {
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultRateLimitIdleTimeout
+	}
+	if config.Key == nil {
+		config.Key = KeyByHost()
+	}
+
+	return &keyedRateLimitTransport{
+		transport: rt,
+		config:    config,
+		limiters:  make(map[string]*keyedLimiter),
+		now:       time.Now,
+	}
+}
+
+// limiter returns the limiter for key, creating it on first use, and marks it
+// active until done is called, so that it is not evicted while requests wait
+// for its tokens. It returns nil if the key is not rate limited.
+func (t *keyedRateLimitTransport) limiter(key string) *keyedLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+
+	if l, ok := t.limiters[key]; ok {
+		l.lastUsed = now
+		l.active++
+		return l
+	}
+
+	config, ok := t.config.Limits[key]
+	if !ok {
+		config = t.config.Default
+	}
+	if config.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	l := &keyedLimiter{
+		bucket:   newTokenBucket(config.RequestsPerSecond, config.Burst),
+		failFast: config.FailFast,
+		lastUsed: now,
+		active:   1,
+	}
+	t.limiters[key] = l
+	return l
+}
+
+// done marks a request returned by limiter as no longer waiting. The idle
+// time of the limiter counts from then.
+func (t *keyedRateLimitTransport) done(l *keyedLimiter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l.active--
+	l.lastUsed = t.now()
+}
+
+// sweep evicts limiters idle for longer than IdleTimeout. Limiters with
+// requests still waiting for a token are never evicted, since a fresh bucket
+// would let the next request bypass the limit. It runs at most once per
+// IdleTimeout. The caller must hold t.mu.
+func (t *keyedRateLimitTransport) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.config.IdleTimeout {
+		return
+	}
+	t.lastSweep = now
+
+	for key, l := range t.limiters {
+		if l.active == 0 && now.Sub(l.lastUsed) >= t.config.IdleTimeout {
+			delete(t.limiters, key)
+		}
+	}
+}
+
+// RoundTrip implements http.RoundTripper by taking a token from the limiter of
+// the request key before delegating to the wrapped transport.
+func (t *keyedRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if l := t.limiter(t.config.Key(req)); l != nil {
+		err := l.bucket.take(req.Context(), l.failFast)
+		t.done(l)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,334 @@
+// Package httpc provides tests for client-side rate limiting.
+// This file contains tests for the token bucket and the rate limiting transport
+// in blocking and fail-fast modes.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Allow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := newTokenBucket(2, 3)
+	bucket.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !bucket.allow() {
+			t.Fatalf("Expected burst token %d to be available", i+1)
+		}
+	}
+
+	if bucket.allow() {
+		t.Fatal("Expected bucket to be empty after burst")
+	}
+
+	// 2 tokens per second: one token after 500ms
+	now = now.Add(500 * time.Millisecond)
+	if !bucket.allow() {
+		t.Error("Expected a token after refill")
+	}
+	if bucket.allow() {
+		t.Error("Expected only one token after 500ms")
+	}
+}
+
+func TestTokenBucket_RefillCappedAtBurst(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := newTokenBucket(100, 2)
+	bucket.now = func() time.Time { return now }
+
+	bucket.allow()
+	now = now.Add(time.Hour)
+
+	allowed := 0
+	for bucket.allow() {
+		allowed++
+	}
+
+	if allowed != 2 {
+		t.Errorf("Expected refill capped at burst 2, got %d", allowed)
+	}
+}
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := newTokenBucket(10, 1)
+	bucket.now = func() time.Time { return now }
+
+	if delay := bucket.reserve(); delay != 0 {
+		t.Errorf("Expected no delay for the first token, got %v", delay)
+	}
+
+	if delay := bucket.reserve(); delay != 100*time.Millisecond {
+		t.Errorf("Expected 100ms delay, got %v", delay)
+	}
+
+	if delay := bucket.reserve(); delay != 200*time.Millisecond {
+		t.Errorf("Expected 200ms delay for the queued token, got %v", delay)
+	}
+}
+
+func TestTokenBucket_WaitExceedsDeadline(t *testing.T) {
+	bucket := newTokenBucket(1, 1)
+	bucket.allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := bucket.wait(ctx)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Expected wait to fail immediately")
+	}
+}
+
+func TestClient_WithRateLimit_Blocking(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRateLimit(RateLimitConfig{RequestsPerSecond: 20, Burst: 1}))
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	// 1 burst token + 3 tokens at 20/s = at least 150ms
+	if elapsed < 140*time.Millisecond {
+		t.Errorf("Expected requests to be paced, took %v", elapsed)
+	}
+
+	if callCount.Load() != 4 {
+		t.Errorf("Expected 4 calls, got %d", callCount.Load())
+	}
+}
+
+func TestClient_WithRateLimit_FailFast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRateLimit(RateLimitConfig{RequestsPerSecond: 1, Burst: 2, FailFast: true}))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("Get() %d failed: %v", i+1, err)
+		}
+	}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestClient_WithRateLimit_ContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRateLimit(RateLimitConfig{RequestsPerSecond: 0.1, Burst: 1}))
+
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := client.GetWithContext(ctx, server.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Expected the wait to stop on cancellation")
+	}
+}
+
+func TestWithRateLimit_Disabled(t *testing.T) {
+	client := NewClient(WithRateLimit(RateLimitConfig{}))
+
+	if _, ok := client.transport.(*http.Transport); !ok {
+		t.Errorf("Expected no rate limiting transport, got %T", client.transport)
+	}
+}
+
+func TestMatchPathTemplate(t *testing.T) {
+	templates := []string{"/users/{id}", "/orders/{id}/items", "/status"}
+
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"/users/42", "/users/{id}", true},
+		{"/users/42/", "/users/{id}", true},
+		{"/orders/7/items", "/orders/{id}/items", true},
+		{"/status", "/status", true},
+		{"/users", "", false},
+		{"/orders/7/payments", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := matchPathTemplate(tt.path, templates)
+			if ok != tt.ok || got != tt.expected {
+				t.Errorf("matchPathTemplate(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRateLimitKeyFuncs(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://api.example.com/users/42", nil)
+	req.Header.Set("X-Tenant-Id", "acme")
+
+	if got := KeyByHost()(req); got != "api.example.com" {
+		t.Errorf("KeyByHost() = %q", got)
+	}
+
+	if got := KeyByHeader("X-Tenant-Id")(req); got != "acme" {
+		t.Errorf("KeyByHeader() = %q", got)
+	}
+
+	if got := KeyByPathTemplate("/users/{id}")(req); got != "api.example.com/users/{id}" {
+		t.Errorf("KeyByPathTemplate() = %q", got)
+	}
+
+	if got := KeyByPathTemplate("/orders/{id}")(req); got != "api.example.com" {
+		t.Errorf("KeyByPathTemplate() without match = %q", got)
+	}
+}
+
+func TestKeyedRateLimit_IndependentKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithKeyedRateLimit(KeyedRateLimitConfig{
+		Key:     KeyByHeader("X-Tenant-Id"),
+		Default: RateLimitConfig{RequestsPerSecond: 0.1, Burst: 1, FailFast: true},
+		Limits: map[string]RateLimitConfig{
+			"vip": {RequestsPerSecond: 0.1, Burst: 3, FailFast: true},
+		},
+	}))
+
+	tenant := func(id string) RequestOption { return Header("X-Tenant-Id", id) }
+
+	if _, err := client.Get(server.URL, tenant("a")); err != nil {
+		t.Fatalf("tenant a: %v", err)
+	}
+	if _, err := client.Get(server.URL, tenant("a")); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("tenant a: expected ErrRateLimited, got %v", err)
+	}
+
+	// Another tenant has its own bucket
+	if _, err := client.Get(server.URL, tenant("b")); err != nil {
+		t.Errorf("tenant b: %v", err)
+	}
+
+	// Per-key override
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get(server.URL, tenant("vip")); err != nil {
+			t.Errorf("vip request %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestKeyedRateLimit_UnlimitedDefault(t *testing.T) {
+	transport := newKeyedRateLimitTransport(defaultTransport(), KeyedRateLimitConfig{
+		Limits: map[string]RateLimitConfig{"limited.example.com": {RequestsPerSecond: 1}},
+	})
+
+	if l := transport.limiter("other.example.com"); l != nil {
+		t.Error("Expected keys without a limit to be unlimited")
+	}
+
+	if l := transport.limiter("limited.example.com"); l == nil {
+		t.Error("Expected a limiter for the configured key")
+	}
+}
+
+func TestKeyedRateLimit_IdleEviction(t *testing.T) {
+	now := time.Unix(1000, 0)
+	transport := newKeyedRateLimitTransport(defaultTransport(), KeyedRateLimitConfig{
+		Default:     RateLimitConfig{RequestsPerSecond: 1},
+		IdleTimeout: time.Minute,
+	})
+	transport.now = func() time.Time { return now }
+
+	transport.done(transport.limiter("a"))
+	now = now.Add(30 * time.Second)
+	transport.done(transport.limiter("b"))
+
+	now = now.Add(45 * time.Second)
+	transport.done(transport.limiter("b"))
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	if _, ok := transport.limiters["a"]; ok {
+		t.Error("Expected idle limiter 'a' to be evicted")
+	}
+	if _, ok := transport.limiters["b"]; !ok {
+		t.Error("Expected active limiter 'b' to be kept")
+	}
+}
+
+func TestKeyedRateLimit_WaitingLimiterNotEvicted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := newKeyedRateLimitTransport(defaultTransport(), KeyedRateLimitConfig{
+		Default:     RateLimitConfig{RequestsPerSecond: 2},
+		IdleTimeout: 10 * time.Millisecond,
+	})
+	client := NewClient(WithInterceptor(func(http.RoundTripper) http.RoundTripper { return transport }))
+
+	// The first request empties the bucket, the second waits ~500ms for a token
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := client.Get(server.URL)
+		waiting <- err
+	}()
+
+	// Well past the idle timeout, the waiting limiter must still be used
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Expected the third request to wait behind the blocked one, took %v", elapsed)
+	}
+	if err := <-waiting; err != nil {
+		t.Errorf("Waiting request failed: %v", err)
+	}
+}
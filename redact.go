@@ -0,0 +1,319 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the redaction policy used by the logging transports to
+// mask secrets in headers, query parameters and request/response bodies.
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// RedactionPolicy describes which values the logging transports (WithLogger,
+// WithSlog and DebugTransport) mask before writing them to logs. Set it for a
+// whole Client with WithRedaction, or per transport through
+// DebugTransport.Redaction and SlogConfig.Redaction. A nil policy means
+// DefaultRedactionPolicy.
+//
+// Policies given to the client or to a transport add to DefaultRedactionPolicy
+// (see WithDefaults), so masking a query parameter never stops masking
+// Authorization or Cookie. The methods of a RedactionPolicy apply only the
+// names it lists.
+//
+// Example:
+//
+//	policy := httpc.RedactionPolicy{
+//		Headers:     []string{"X-Session"},
+//		QueryParams: []string{"signature"},
+//		BodyFields:  []string{"user.ssn", "cards.number"},
+//	}
+//	client := httpc.NewClient(httpc.WithDebug(), httpc.WithRedaction(policy))
+type RedactionPolicy struct {
+	// Headers are header names whose values are masked. Matching is
+	// case-insensitive and by substring, so "authorization" also masks
+	// "Proxy-Authorization".
+	Headers []string
+
+	// QueryParams are query parameter names whose values are masked in URLs.
+	// Matching is case-insensitive.
+	QueryParams []string
+
+	// BodyFields are field paths whose values are masked in JSON and form
+	// bodies. A path such as "user.password" matches from the root of a JSON
+	// document, with arrays traversed transparently; a single name such as
+	// "password" matches that field at any depth and the form field of that name.
+	// Matching is case-insensitive.
+	BodyFields []string
+
+	// Mask replaces masked values. If empty, header values are replaced with
+	// "***MODIFIED***SENSITIVE HEADER***" and other values with "***REDACTED***".
+	Mask string
+}
+
+// Default masks used when RedactionPolicy.Mask is empty.
+const (
+	sensitiveHeaderMask = "***MODIFIED***SENSITIVE HEADER***"
+	redactedValueMask   = "***REDACTED***"
+)
+
+// DefaultRedactionPolicy returns the policy used when none is configured. It
+// masks common credential headers, token query parameters and password and
+// token body fields.
+func DefaultRedactionPolicy() *RedactionPolicy {
+	return &RedactionPolicy{
+		Headers:     []string{"authorization", "api-key", "x-api-key", "cookie"},
+		QueryParams: []string{"access_token", "api_key", "apikey", "token", "password"},
+		BodyFields: []string{
+			"password", "secret", "client_secret", "token", "access_token", "refresh_token",
+		},
+	}
+}
+
+// WithDefaults returns a copy of p whose headers, query parameters and body
+// fields also include those of DefaultRedactionPolicy. The mask of p is kept.
+//
+// Example:
+//
+//	policy := (&httpc.RedactionPolicy{QueryParams: []string{"signature"}}).WithDefaults()
+//	policy.RedactHeader("Authorization", "Bearer abc") // masked
+func (p *RedactionPolicy) WithDefaults() *RedactionPolicy {
+	defaults := DefaultRedactionPolicy()
+	if p == nil {
+		return defaults
+	}
+
+	return &RedactionPolicy{
+		Headers:     appendFold(defaults.Headers, p.Headers),
+		QueryParams: appendFold(defaults.QueryParams, p.QueryParams),
+		BodyFields:  appendFold(defaults.BodyFields, p.BodyFields),
+		Mask:        p.Mask,
+	}
+}
+
+// redactionKey is the context key under which RequestBuilder.Do stores the
+// Client redaction policy.
+type redactionKey struct{}
+
+// redactionFrom returns the policy a logging transport should apply to a
+// request: its own policy if set, merged with the defaults, then the Client
+// policy stored in ctx, then DefaultRedactionPolicy.
+func redactionFrom(ctx context.Context, own *RedactionPolicy) *RedactionPolicy {
+	if own != nil {
+		return own.WithDefaults()
+	}
+	if policy, ok := ctx.Value(redactionKey{}).(*RedactionPolicy); ok {
+		return policy
+	}
+	return DefaultRedactionPolicy()
+}
+
+// orDefault returns p, or DefaultRedactionPolicy if p is nil.
+func (p *RedactionPolicy) orDefault() *RedactionPolicy {
+	if p == nil {
+		return DefaultRedactionPolicy()
+	}
+	return p
+}
+
+// mask returns the configured mask, or fallback if none is set.
+func (p *RedactionPolicy) mask(fallback string) string {
+	if p.Mask != "" {
+		return p.Mask
+	}
+	return fallback
+}
+
+// IsSensitiveHeader reports whether the values of header are masked.
+func (p *RedactionPolicy) IsSensitiveHeader(header string) bool {
+	p = p.orDefault()
+	lower := strings.ToLower(header)
+	for _, name := range p.Headers {
+		if strings.Contains(lower, strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeader returns value, or the mask if header is sensitive.
+func (p *RedactionPolicy) RedactHeader(header, value string) string {
+	p = p.orDefault()
+	if p.IsSensitiveHeader(header) {
+		return p.mask(sensitiveHeaderMask)
+	}
+	return value
+}
+
+// RedactURL returns u as a string with the values of sensitive query
+// parameters masked.
+func (p *RedactionPolicy) RedactURL(u *url.URL) string {
+	p = p.orDefault()
+	if u == nil {
+		return ""
+	}
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return u.String()
+	}
+
+	redacted := false
+	for name, values := range query {
+		if !containsFold(p.QueryParams, name) {
+			continue
+		}
+		for i := range values {
+			values[i] = p.mask(redactedValueMask)
+		}
+		redacted = true
+	}
+	if !redacted {
+		return u.String()
+	}
+
+	masked := *u
+	masked.RawQuery = query.Encode()
+	return masked.String()
+}
+
+// RedactBody returns body with sensitive fields masked. JSON bodies and
+// application/x-www-form-urlencoded bodies are supported, as indicated by
+// contentType or, when it is empty, sniffed from the body. Other bodies, and
+// bodies without sensitive fields, are returned unchanged.
+func (p *RedactionPolicy) RedactBody(body []byte, contentType string) []byte {
+	p = p.orDefault()
+	if len(body) == 0 || len(p.BodyFields) == 0 {
+		return body
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	trimmed := bytes.TrimSpace(body)
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return p.redactForm(body)
+	case strings.HasSuffix(mediaType, "json"),
+		mediaType == "" && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		return p.redactJSON(body)
+	default:
+		return body
+	}
+}
+
+// redactForm masks the sensitive fields of a form-encoded body.
+func (p *RedactionPolicy) redactForm(body []byte) []byte {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+
+	redacted := false
+	for name, values := range form {
+		if !containsFold(p.BodyFields, name) {
+			continue
+		}
+		for i := range values {
+			values[i] = p.mask(redactedValueMask)
+		}
+		redacted = true
+	}
+	if !redacted {
+		return body
+	}
+	return []byte(form.Encode())
+}
+
+// redactJSON masks the sensitive fields of a JSON body.
+func (p *RedactionPolicy) redactJSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return body
+	}
+
+	if !p.redactValue(doc, nil) {
+		return body
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redactValue masks sensitive fields within v, whose location in the document
+// is path, and reports whether anything was masked.
+func (p *RedactionPolicy) redactValue(v any, path []string) bool {
+	redacted := false
+
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			fieldPath := append(path[:len(path):len(path)], key)
+			if p.matchesField(fieldPath) {
+				v[key] = p.mask(redactedValueMask)
+				redacted = true
+				continue
+			}
+			if p.redactValue(value, fieldPath) {
+				redacted = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if p.redactValue(item, path) {
+				redacted = true
+			}
+		}
+	}
+
+	return redacted
+}
+
+// matchesField reports whether the JSON field at path is sensitive.
+func (p *RedactionPolicy) matchesField(path []string) bool {
+	for _, field := range p.BodyFields {
+		if !strings.Contains(field, ".") {
+			if strings.EqualFold(field, path[len(path)-1]) {
+				return true
+			}
+			continue
+		}
+
+		if strings.EqualFold(field, strings.Join(path, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether names contains name, ignoring case.
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendFold returns a new slice with names followed by the extra names not
+// already present, compared case-insensitively.
+func appendFold(names, extra []string) []string {
+	merged := append([]string(nil), names...)
+	for _, name := range extra {
+		if !containsFold(merged, name) {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,216 @@
+// Package httpc provides tests for log redaction.
+// This file contains tests for the RedactionPolicy and its use by the
+// logging transports.
+package httpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactionPolicy_RedactHeader(t *testing.T) {
+	policy := &RedactionPolicy{Headers: []string{"x-session"}, Mask: "[hidden]"}
+
+	if got := policy.RedactHeader("X-Session-Id", "abc"); got != "[hidden]" {
+		t.Errorf("Expected custom header to be masked, got %q", got)
+	}
+	if got := policy.RedactHeader("Authorization", "Bearer abc"); got != "Bearer abc" {
+		t.Errorf("Expected headers outside the policy to be kept, got %q", got)
+	}
+
+	// A nil policy falls back to the defaults
+	var defaults *RedactionPolicy
+	if got := defaults.RedactHeader("Proxy-Authorization", "secret"); got != sensitiveHeaderMask {
+		t.Errorf("Expected default policy to mask Proxy-Authorization, got %q", got)
+	}
+}
+
+func TestRedactionPolicy_RedactURL(t *testing.T) {
+	policy := &RedactionPolicy{QueryParams: []string{"Token", "sig"}}
+
+	u, _ := url.Parse("https://api.example.com/users?token=abc&page=2&SIG=xyz")
+	got := policy.RedactURL(u)
+
+	if strings.Contains(got, "abc") || strings.Contains(got, "xyz") {
+		t.Errorf("Expected query values to be masked, got %q", got)
+	}
+	if !strings.Contains(got, "page=2") {
+		t.Errorf("Expected other parameters to be kept, got %q", got)
+	}
+	if u.RawQuery != "token=abc&page=2&SIG=xyz" {
+		t.Errorf("RedactURL must not modify the URL, got %q", u.RawQuery)
+	}
+
+	plain, _ := url.Parse("https://api.example.com/users?page=2")
+	if got := policy.RedactURL(plain); got != plain.String() {
+		t.Errorf("Expected URL without sensitive parameters to be unchanged, got %q", got)
+	}
+}
+
+func TestRedactionPolicy_RedactBody_JSON(t *testing.T) {
+	policy := &RedactionPolicy{BodyFields: []string{"password", "card.number"}}
+
+	body := []byte(`{"user":"john","password":"hunter2","card":{"number":"4111","expiry":"12/30"},` +
+		`"items":[{"password":"p1"}],"other":{"card":{"number":"keep"}}}`)
+	got := policy.RedactBody(body, "application/json; charset=utf-8")
+
+	var doc map[string]any
+	if err := json.Unmarshal(got, &doc); err != nil {
+		t.Fatalf("Redacted body is not valid JSON: %v", err)
+	}
+
+	if doc["password"] != redactedValueMask {
+		t.Errorf("Expected top-level password to be masked, got %v", doc["password"])
+	}
+	if card := doc["card"].(map[string]any); card["number"] != redactedValueMask || card["expiry"] != "12/30" {
+		t.Errorf("Expected only card.number to be masked, got %v", card)
+	}
+	if item := doc["items"].([]any)[0].(map[string]any); item["password"] != redactedValueMask {
+		t.Errorf("Expected password in arrays to be masked, got %v", item)
+	}
+	if nested := doc["other"].(map[string]any)["card"].(map[string]any); nested["number"] != "keep" {
+		t.Errorf("Expected dotted paths to match from the root only, got %v", nested)
+	}
+
+	untouched := []byte(`{"user": "john"}`)
+	if got := policy.RedactBody(untouched, ""); !bytes.Equal(got, untouched) {
+		t.Errorf("Expected body without sensitive fields to be unchanged, got %s", got)
+	}
+}
+
+func TestRedactionPolicy_RedactBody_Form(t *testing.T) {
+	policy := &RedactionPolicy{BodyFields: []string{"password"}}
+
+	got := string(policy.RedactBody([]byte("user=john&password=hunter2"), "application/x-www-form-urlencoded"))
+	if strings.Contains(got, "hunter2") || !strings.Contains(got, "user=john") {
+		t.Errorf("Expected password form field to be masked, got %q", got)
+	}
+
+	text := []byte("password=hunter2")
+	if got := policy.RedactBody(text, "text/plain"); !bytes.Equal(got, text) {
+		t.Errorf("Expected unsupported content types to be unchanged, got %q", got)
+	}
+}
+
+func TestDebugTransport_Redaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"server-secret","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	var logBuf bytes.Buffer
+	policy := DefaultRedactionPolicy()
+	policy.Headers = append(policy.Headers, "x-session")
+
+	client := NewClient(
+		WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+			dt := NewDebugTransport(rt, true)
+			dt.Logger = log.New(&logBuf, "", 0)
+			return dt
+		}),
+		WithRedaction(*policy),
+	)
+
+	resp, err := client.NewRequest().
+		Method(http.MethodPost).
+		URL(server.URL+"/login?token=query-secret").
+		Header("X-Session", "session-secret").
+		JSON(map[string]string{"user": "john", "password": "body-secret"}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	output := logBuf.String()
+	for _, secret := range []string{"query-secret", "session-secret", "body-secret", "server-secret"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %q to be redacted from debug output:\n%s", secret, output)
+		}
+	}
+	if !strings.Contains(output, "john") || !strings.Contains(output, "3600") {
+		t.Errorf("Expected non-sensitive values to be logged:\n%s", output)
+	}
+}
+
+func TestLoggingTransports_RedactURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var logBuf, slogBuf bytes.Buffer
+	client := NewClient(
+		WithLogger(log.New(&logBuf, "", 0)),
+		WithSlog(SlogConfig{Logger: slog.New(slog.NewJSONHandler(&slogBuf, nil))}),
+		WithRedaction(RedactionPolicy{QueryParams: []string{"signature"}}),
+	)
+
+	if _, err := client.Get(server.URL + "?signature=s3cr3t&page=1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	for name, output := range map[string]string{"WithLogger": logBuf.String(), "WithSlog": slogBuf.String()} {
+		if strings.Contains(output, "s3cr3t") {
+			t.Errorf("%s logged the signature: %s", name, output)
+		}
+		if !strings.Contains(output, "page=1") {
+			t.Errorf("%s dropped non-sensitive parameters: %s", name, output)
+		}
+	}
+}
+
+func TestWithRedaction_KeepsDefaults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var logBuf bytes.Buffer
+	debug := NewDebugTransport(nil, true)
+	debug.Logger = log.New(&logBuf, "", 0)
+	client := NewClient(
+		WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+			debug.transport = rt
+			return debug
+		}),
+		WithRedaction(RedactionPolicy{QueryParams: []string{"signature"}}),
+	)
+
+	_, err := client.NewRequest().
+		URL(server.URL+"?signature=s3cr3t&token=t0ken").
+		Header("Authorization", "Bearer header-secret").
+		Header("Cookie", "session=cookie-secret").
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	output := logBuf.String()
+	for _, secret := range []string{"s3cr3t", "t0ken", "header-secret", "cookie-secret"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %q to be masked in debug output:\n%s", secret, output)
+		}
+	}
+}
+
+func TestRedactionPolicy_WithDefaults(t *testing.T) {
+	policy := (&RedactionPolicy{Headers: []string{"X-Session", "COOKIE"}, Mask: "[hidden]"}).WithDefaults()
+
+	for _, header := range []string{"Authorization", "Cookie", "X-Session-Id"} {
+		if got := policy.RedactHeader(header, "secret"); got != "[hidden]" {
+			t.Errorf("Expected %s to be masked, got %q", header, got)
+		}
+	}
+	if len(policy.Headers) != len(DefaultRedactionPolicy().Headers)+1 {
+		t.Errorf("Expected duplicate names to be merged, got %v", policy.Headers)
+	}
+}
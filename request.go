@@ -24,15 +24,17 @@ import (
 //	    Timeout(10*time.Second).
 //	    Do()
 type RequestBuilder struct {
-	client  *Client
-	method  string
-	url     string
-	headers map[string]string
-	query   url.Values
-	body    io.Reader
-	timeout time.Duration
-	ctx     context.Context
-	err     error
+	client   *Client
+	method   string
+	url      string
+	headers  map[string]string
+	query    url.Values
+	body     io.Reader
+	timeout  time.Duration
+	ctx      context.Context
+	retry    *RetryConfig
+	priority Priority
+	err      error
 }
 
 // NewRequest creates a new RequestBuilder for building and executing HTTP requests.
@@ -152,6 +154,40 @@ func (rb *RequestBuilder) Context(ctx context.Context) *RequestBuilder {
 	return rb
 }
 
+// Retry overrides the client's retry configuration for this request only.
+// The override also applies on clients without WithRetry; the client's retry
+// budget, if any, still applies and config.Budget is ignored.
+//
+// Example:
+//
+//	rb.Retry(httpc.RetryConfig{MaxRetries: 5, Backoff: time.Second})
+func (rb *RequestBuilder) Retry(config RetryConfig) *RequestBuilder {
+	rb.retry = &config
+	return rb
+}
+
+// NoRetry disables retries for this request, even if the client was configured
+// with WithRetry. Use it for calls that must never be sent twice.
+//
+// Example:
+//
+//	rb.Method("POST").URL("/payments").JSON(payment).NoRetry()
+func (rb *RequestBuilder) NoRetry() *RequestBuilder {
+	return rb.Retry(RetryConfig{})
+}
+
+// Priority sets the priority of the request in the concurrency limiter queue
+// (see WithMaxConcurrency). Higher-priority requests are started before
+// lower-priority ones waiting for a slot. The default is PriorityNormal.
+//
+// Example:
+//
+//	rb.Priority(httpc.PriorityHigh)
+func (rb *RequestBuilder) Priority(priority Priority) *RequestBuilder {
+	rb.priority = priority
+	return rb
+}
+
 // buildURL builds the request URL
 func (rb *RequestBuilder) buildURL() string {
 	fullURL := rb.resolveURL()
@@ -284,6 +320,16 @@ func (rb *RequestBuilder) Do() (*Response, error) {
 		defer cancel()
 	}
 
+	// Carry the per-request retry override to the retry transport
+	if rb.retry != nil {
+		ctx = withRetryOverride(ctx, rb.retry)
+	}
+
+	// Carry the priority to the concurrency limiter
+	if rb.priority != PriorityNormal {
+		ctx = withPriority(ctx, rb.priority)
+	}
+
 	// Create the HTTP request
 	req, err := http.NewRequestWithContext(ctx, rb.method, fullURL, rb.body)
 
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -448,3 +449,129 @@ func TestRequestBuilder_Do_WithNetworkError(t *testing.T) {
 		t.Error("Expected network error for invalid domain")
 	}
 }
+
+func TestRequestBuilder_Retry_Override(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 1,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+
+	_, err := client.NewRequest().
+		Method("GET").
+		URL(server.URL).
+		Retry(RetryConfig{MaxRetries: 4, Backoff: time.Millisecond, RetryIf: defaultRetryCondition}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	// Override allows 4 retries instead of the client's 1
+	if callCount.Load() != 5 {
+		t.Errorf("Expected 5 calls, got %d", callCount.Load())
+	}
+}
+
+func TestRequestBuilder_NoRetry(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+
+	resp, err := client.NewRequest().Method("GET").URL(server.URL).NoRetry().Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+
+	if callCount.Load() != 1 {
+		t.Errorf("Expected 1 call with retries disabled, got %d", callCount.Load())
+	}
+
+	// Other requests still use the client configuration
+	callCount.Store(0)
+	_, _ = client.NewRequest().Method("GET").URL(server.URL).Do()
+	if callCount.Load() != 4 {
+		t.Errorf("Expected 4 calls with client retries, got %d", callCount.Load())
+	}
+}
+
+func TestRequestBuilder_Retry_DefaultCondition(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 1,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+
+	// A nil RetryIf falls back to the default retry condition
+	resp, err := client.NewRequest().
+		Method("GET").
+		URL(server.URL).
+		Retry(RetryConfig{MaxRetries: 2, Backoff: time.Millisecond}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	if callCount.Load() != 3 || resp.Attempts() != 3 {
+		t.Errorf("Expected 3 calls, got %d (attempts %d)", callCount.Load(), resp.Attempts())
+	}
+}
+
+func TestRequestBuilder_Retry_WithoutWithRetry(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+
+	// Requests are sent once by default
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if callCount.Load() != 1 {
+		t.Fatalf("Expected 1 call without retries, got %d", callCount.Load())
+	}
+
+	callCount.Store(0)
+	resp, err := client.Get(server.URL, WithRequestRetry(RetryConfig{MaxRetries: 2, Backoff: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if callCount.Load() != 3 || resp.Attempts() != 3 {
+		t.Errorf("Expected the override to retry twice, got %d calls (attempts %d)", callCount.Load(), resp.Attempts())
+	}
+}
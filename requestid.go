@@ -0,0 +1,132 @@
+// Package httpc provides HTTP client functionality.
+// This file contains per-request ID generation and propagation.
+package httpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RequestIDGenerator returns a new, unique request ID.
+// NewUUIDv4, NewUUIDv7 and NewULID are ready-made generators.
+type RequestIDGenerator func() string
+
+// requestIDConfig is the request ID configuration set by WithRequestId and
+// WithRequestIdGenerator.
+type requestIDConfig struct {
+	header    string
+	generator RequestIDGenerator
+}
+
+// requestIDKey is the context key under which the request ID is stored.
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying id. Requests made with
+// it by a client configured with WithRequestId or WithRequestIdGenerator
+// reuse id instead of generating a new one, so that an ID received by a
+// server can be propagated to its outgoing calls.
+//
+// Example:
+//
+//	ctx := httpc.ContextWithRequestID(r.Context(), r.Header.Get("X-Request-Id"))
+//	resp, err := client.NewRequest().Context(ctx).URL("/users").Do()
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty string.
+// Within interceptors, it returns the ID of the request being sent.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDSuffix returns " [<id>]" for the request ID stored in ctx, to tag
+// log lines, or an empty string if there is none.
+func requestIDSuffix(ctx context.Context) string {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return " [" + id + "]"
+	}
+	return ""
+}
+
+// resolve returns the ID of req: the value of the request ID header if the
+// request already has one, then the ID stored in its context, then a newly
+// generated one.
+func (c *requestIDConfig) resolve(req *http.Request) string {
+	if id := req.Header.Get(c.header); id != "" {
+		return id
+	}
+	if id := RequestIDFromContext(req.Context()); id != "" {
+		return id
+	}
+	return c.generator()
+}
+
+// NewUUIDv4 returns a random RFC 9562 version 4 UUID, such as
+// "9b2f0c1e-5d3a-4c8e-a1f7-3e6b2d9c4a10".
+func NewUUIDv4() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 9562 variant
+	return formatUUID(b)
+}
+
+// NewUUIDv7 returns an RFC 9562 version 7 UUID, which starts with the current
+// Unix time in milliseconds, so that IDs sort by creation time.
+func NewUUIDv7() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	putUnixMilli(b[:6], time.Now())
+	b[6] = (b[6] & 0x0f) | 0x70 // version 7
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 9562 variant
+	return formatUUID(b)
+}
+
+// formatUUID returns b in the canonical 8-4-4-4-12 hex form of a UUID.
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// NewULID returns a ULID: a 48-bit Unix time in milliseconds followed by 80
+// random bits, encoded as 26 Crockford base32 characters, such as
+// "01HZX3J8Q4V6T2N9C5R7W0K1MB". ULIDs sort by creation time.
+func NewULID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	putUnixMilli(b[:6], time.Now())
+	return encodeCrockford(b)
+}
+
+// putUnixMilli writes the Unix time of t in milliseconds to the 6 bytes of dst, big-endian.
+func putUnixMilli(dst []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(dst, ms[2:])
+}
+
+// crockfordAlphabet is the Crockford base32 alphabet used by ULIDs.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// encodeCrockford encodes the 128 bits of b as 26 Crockford base32
+// characters, the first of which only carries the 3 most significant bits.
+func encodeCrockford(b [16]byte) string {
+	out := make([]byte, 26)
+	for i := range out {
+		var v byte
+		// 26 characters hold 130 bits, so the first group starts 2 bits before b
+		for bit := i*5 - 2; bit < i*5+3; bit++ {
+			v <<= 1
+			if bit >= 0 {
+				v |= (b[bit/8] >> (7 - bit%8)) & 1
+			}
+		}
+		out[i] = crockfordAlphabet[v]
+	}
+	return string(out)
+}
@@ -0,0 +1,201 @@
+// Package httpc provides tests for request IDs.
+// This file contains tests for the request ID generators and for per-request
+// IDs set by WithRequestId and WithRequestIdGenerator.
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewUUIDv4(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewUUIDv4()
+		if !pattern.MatchString(id) {
+			t.Fatalf("NewUUIDv4() = %q, not a valid version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewUUIDv4() returned duplicate %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewUUIDv7(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first := NewUUIDv7()
+	time.Sleep(2 * time.Millisecond)
+	second := NewUUIDv7()
+
+	for _, id := range []string{first, second} {
+		if !pattern.MatchString(id) {
+			t.Errorf("NewUUIDv7() = %q, not a valid version 7 UUID", id)
+		}
+	}
+	if first >= second {
+		t.Errorf("Expected UUIDv7 to sort by time, got %q then %q", first, second)
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	first := NewULID()
+	time.Sleep(2 * time.Millisecond)
+	second := NewULID()
+
+	for _, id := range []string{first, second} {
+		if !pattern.MatchString(id) {
+			t.Errorf("NewULID() = %q, not a valid ULID", id)
+		}
+	}
+	if first[:10] >= second[:10] {
+		t.Errorf("Expected ULID timestamps to sort by time, got %q then %q", first, second)
+	}
+}
+
+func TestEncodeCrockford(t *testing.T) {
+	var b [16]byte
+	if got := encodeCrockford(b); got != strings.Repeat("0", 26) {
+		t.Errorf("Expected zero ULID, got %q", got)
+	}
+
+	for i := range b {
+		b[i] = 0xff
+	}
+	if got := encodeCrockford(b); got != "7"+strings.Repeat("Z", 25) {
+		t.Errorf("Expected max ULID, got %q", got)
+	}
+}
+
+func TestClient_WithRequestId_PerRequest(t *testing.T) {
+	var mu sync.Mutex
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ids = append(ids, r.Header.Get("X-Request-Id"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRequestId(""))
+
+	var responses []*Response
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		if id == "" || seen[id] {
+			t.Errorf("Expected a unique ID per request, got %v", ids)
+		}
+		seen[id] = true
+		if responses[i].RequestID() != id {
+			t.Errorf("Expected Response.RequestID() %q, got %q", id, responses[i].RequestID())
+		}
+	}
+}
+
+func TestClient_WithRequestIdGenerator_Reuse(t *testing.T) {
+	var received atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Store(r.Header.Get("X-Correlation-Id"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRequestIdGenerator("X-Correlation-Id", func() string { return "generated" }))
+
+	resp, err := client.Get(server.URL)
+	if err != nil || resp.RequestID() != "generated" || received.Load() != "generated" {
+		t.Errorf("Expected the generator to be used, got %q (err %v)", received.Load(), err)
+	}
+
+	// An ID in the context is reused
+	ctx := ContextWithRequestID(context.Background(), "from-context")
+	resp, err = client.NewRequest().Context(ctx).URL(server.URL).Do()
+	if err != nil || resp.RequestID() != "from-context" || received.Load() != "from-context" {
+		t.Errorf("Expected the context ID to be reused, got %q (err %v)", received.Load(), err)
+	}
+
+	// An ID set on the request is kept
+	resp, err = client.NewRequest().URL(server.URL).Header("X-Correlation-Id", "explicit").Do()
+	if err != nil || resp.RequestID() != "explicit" || received.Load() != "explicit" {
+		t.Errorf("Expected the request header to be kept, got %q (err %v)", received.Load(), err)
+	}
+}
+
+func TestClient_WithRequestId_Retries(t *testing.T) {
+	var mu sync.Mutex
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ids = append(ids, r.Header.Get("X-Request-Id"))
+		attempt := len(ids)
+		mu.Unlock()
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var logBuf bytes.Buffer
+	client := NewClient(
+		WithRequestId(""),
+		WithRetry(RetryConfig{
+			MaxRetries: 3,
+			Backoff:    time.Millisecond,
+			RetryIf:    defaultRetryCondition,
+		}),
+		WithLogger(log.New(&logBuf, "", 0)),
+	)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if len(ids) != 3 || ids[0] != ids[1] || ids[1] != ids[2] || ids[0] != resp.RequestID() {
+		t.Errorf("Expected all attempts to share the request ID, got %v", ids)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(logBuf.String()), "\n") {
+		if !strings.HasSuffix(line, "["+resp.RequestID()+"]") {
+			t.Errorf("Expected log line to carry the request ID: %q", line)
+		}
+	}
+}
+
+func TestResponse_RequestID_Disabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Request-Id") != "" {
+			t.Error("Expected no request ID header without WithRequestId")
+		}
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if resp.RequestID() != "" {
+		t.Errorf("Expected empty RequestID, got %q", resp.RequestID())
+	}
+}
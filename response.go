@@ -17,8 +17,14 @@ import (
 // return the same data without re-reading.
 type Response struct {
 	*http.Response
-	body         []byte
-	csvSeparator rune
+	body          []byte
+	csvSeparator  rune
+	attempts      int
+	attemptErrors []error
+	timings       *timingRecorder
+	requestID     string
+	fromCache     bool
+	revalidated   bool
 }
 
 // Bytes returns the response body as a byte slice.
@@ -252,6 +258,98 @@ func (r *Response) CSV(v interface{}) error {
 	return nil
 }
 
+// Attempts returns the number of attempts made to obtain this response,
+// including the first one. It is greater than 1 when the response was
+// produced by a retry (see WithRetry).
+//
+// Example:
+//
+//	resp, err := client.Get("/api/users")
+//	if err == nil && resp.Attempts() > 1 {
+//		log.Printf("succeeded after %d attempts", resp.Attempts())
+//	}
+func (r *Response) Attempts() int {
+	if r.attempts == 0 {
+		return 1
+	}
+	return r.attempts
+}
+
+// AttemptErrors returns the errors of the attempts that were retried before
+// this response, in order. Attempts that failed with a retryable status code
+// are reported as *Error. The slice is empty when no retry happened.
+//
+// Example:
+//
+//	for i, err := range resp.AttemptErrors() {
+//		log.Printf("attempt %d failed: %v", i+1, err)
+//	}
+func (r *Response) AttemptErrors() []error {
+	return r.attemptErrors
+}
+
+// RequestID returns the ID sent with the request in the request ID header.
+// It returns an empty string unless the client was created with WithRequestId
+// or WithRequestIdGenerator.
+//
+// Example:
+//
+//	resp, err := client.Get("/api/users")
+//	if err == nil && resp.StatusCode >= 400 {
+//		log.Printf("request %s failed with status %d", resp.RequestID(), resp.StatusCode)
+//	}
+func (r *Response) RequestID() string {
+	return r.requestID
+}
+
+// FromCache reports whether the response was served from the cache set up
+// with WithCache, either because the stored response was fresh or because the
+// server confirmed it with a 304 Not Modified (see Revalidated).
+//
+// Example:
+//
+//	resp, err := client.Get("/api/countries")
+//	if err == nil && resp.FromCache() {
+//		log.Println("served from cache")
+//	}
+func (r *Response) FromCache() bool {
+	return r.fromCache
+}
+
+// Revalidated reports whether the server answered the request with a 304 Not
+// Modified, and the response is the stored copy of an earlier response,
+// confirmed by the server (see WithRevalidation and WithCache).
+//
+// Example:
+//
+//	resp, err := client.Get("/api/countries")
+//	if err == nil && resp.Revalidated() {
+//		log.Println("not modified since the last request")
+//	}
+func (r *Response) Revalidated() bool {
+	return r.revalidated
+}
+
+// Timings returns the breakdown of the request duration into DNS, connect,
+// TLS handshake, time to first byte and body read, and whether the connection
+// was reused. It returns nil unless the client was created with WithTimings.
+// BodyRead and Total are final once the body has been read or closed.
+//
+// Example:
+//
+//	resp, err := client.Get("/api/users")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	body, _ := resp.Bytes()
+//	if t := resp.Timings(); t != nil {
+//		log.Printf("dns=%v connect=%v tls=%v ttfb=%v read=%v reused=%v",
+//			t.DNS, t.Connect, t.TLSHandshake, t.TimeToFirstByte, t.BodyRead, t.ConnReused)
+//	}
+func (r *Response) Timings() *Timings {
+	return r.timings.snapshot()
+}
+
 // isSuccess returns true if the response status code is in the 2xx range.
 func (r *Response) isSuccess() bool {
 	return r.StatusCode >= 200 && r.StatusCode < 300
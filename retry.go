@@ -1,10 +1,26 @@
 package httpc
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// defaultMaxRetryWait bounds server-requested retry delays when
+// RetryConfig.MaxRetryWait is not set.
+const defaultMaxRetryWait = time.Minute
+
+// defaultMaxBufferedBodySize is the request body buffering limit used when
+// RetryConfig.MaxBufferedBodySize is not set.
+const defaultMaxBufferedBodySize = 1 << 20
+
 // RetryConfig configures the retry behavior for failed HTTP requests.
 // It defines the maximum number of retries, backoff duration, and
 // a custom condition function to determine if a request should be retried.
@@ -12,17 +28,169 @@ type RetryConfig struct {
 	// MaxRetries is the maximum number of retry attempts
 	MaxRetries int
 
-	// Backoff is the base duration between retries (multiplied by attempt number for exponential backoff)
+	// Backoff is the base duration between retries. When BackoffStrategy is nil,
+	// the wait grows linearly: Backoff * (attempt + 1).
 	Backoff time.Duration
 
+	// BackoffStrategy computes the wait before each retry. If nil, a linear
+	// schedule based on Backoff is used. See ExponentialBackoff, FullJitterBackoff,
+	// EqualJitterBackoff and DecorrelatedJitterBackoff for jittered alternatives.
+	BackoffStrategy BackoffStrategy
+
+	// MaxRetryWait bounds how long the client waits when the server asks for
+	// a specific delay through the Retry-After or X-RateLimit-Reset headers on
+	// a 429 or 503 response. Longer requested waits are capped at this value.
+	// If zero, a default of one minute is used.
+	MaxRetryWait time.Duration
+
+	// AttemptTimeout bounds each individual attempt, including reading its
+	// response body, so a single hung attempt cannot consume the whole request
+	// timeout. A timed out attempt is retried like any other network error.
+	// If zero, attempts are only bounded by the request context and client timeout.
+	AttemptTimeout time.Duration
+
 	// RetryIf is a function that determines whether a request should be retried
-	// based on the response or error. If nil, defaultRetryCondition is used.
+	// based on the response or error. If nil, the default retry condition is
+	// used: network errors, 5xx and 429 responses are retried.
 	RetryIf func(*http.Response, error) bool
+
+	// RetryNonIdempotent allows retrying requests whose method is not idempotent
+	// (POST, PATCH, CONNECT). By default only GET, HEAD, OPTIONS, TRACE, PUT and
+	// DELETE requests, or requests carrying an Idempotency-Key header, are retried,
+	// since replaying other methods may duplicate side effects on the server.
+	RetryNonIdempotent bool
+
+	// IdempotencyKeyHeader, when set, attaches a generated key under this header
+	// (typically "Idempotency-Key") to non-idempotent requests that do not already
+	// carry one. The same key is sent on every attempt of the request, which makes
+	// such requests eligible for retry.
+	IdempotencyKeyHeader string
+
+	// Budget, when set, caps the retries issued by all requests of the Client
+	// relative to its recent request volume. Retries that exceed the budget are
+	// skipped and the last response or error is returned. See RetryBudget.
+	Budget *RetryBudget
+
+	// MaxBufferedBodySize is the largest request body, in bytes, that is buffered
+	// in memory so it can be replayed on retry. Bodies created from a bytes.Buffer,
+	// bytes.Reader or strings.Reader (including RequestBuilder.JSON) are replayed
+	// through http.Request.GetBody and are never buffered. Larger streamed bodies
+	// are sent once and are not retried. If zero, 1 MiB is used; a negative value
+	// disables buffering.
+	MaxBufferedBodySize int64
+
+	// OnRetry, if set, is called before waiting for each retry with the attempt
+	// that failed, its error or status code, and the delay chosen before the next
+	// attempt. It is called synchronously from the request goroutine, so it
+	// should return quickly.
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt that is about to be retried.
+// It is passed to RetryConfig.OnRetry.
+type RetryEvent struct {
+	// Request is the request being retried.
+	Request *http.Request
+
+	// Attempt is the 1-based number of the attempt that failed.
+	Attempt int
+
+	// Err is the error of the failed attempt. For attempts that returned a
+	// retryable response, it is an *Error carrying the status code.
+	Err error
+
+	// StatusCode is the response status of the failed attempt, or 0 if the
+	// attempt failed without a response.
+	StatusCode int
+
+	// Delay is the wait chosen before the next attempt.
+	Delay time.Duration
+}
+
+// newRetryEvent builds the RetryEvent for a failed attempt.
+func newRetryEvent(req *http.Request, attempt int, resp *http.Response, err error, delay time.Duration) RetryEvent {
+	event := RetryEvent{
+		Request: req,
+		Attempt: attempt,
+		Err:     err,
+		Delay:   delay,
+	}
+
+	if resp != nil {
+		event.StatusCode = resp.StatusCode
+		if err == nil {
+			event.Err = &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+	}
+
+	return event
+}
+
+// attemptRecorderKey is the context key under which RequestBuilder.Do stores
+// the attemptRecorder for a request.
+type attemptRecorderKey struct{}
+
+// attemptRecorder collects the number of attempts and the errors of retried
+// attempts for a single request, so they can be exposed on the Response.
+// All methods are safe to call on a nil recorder.
+type attemptRecorder struct {
+	mu       sync.Mutex
+	attempts int
+	errors   []error
+}
+
+// attemptRecorderFrom returns the recorder stored in ctx, or nil.
+func attemptRecorderFrom(ctx context.Context) *attemptRecorder {
+	recorder, _ := ctx.Value(attemptRecorderKey{}).(*attemptRecorder)
+	return recorder
+}
+
+// reset clears the recorder at the start of a round trip, so that only the
+// attempts for the final hop of a redirect chain are reported.
+func (r *attemptRecorder) reset() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = 0
+	r.errors = nil
+}
+
+// recordAttempt counts an attempt that was sent.
+func (r *attemptRecorder) recordAttempt() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+}
+
+// recordRetry stores the error of an attempt that is being retried.
+func (r *attemptRecorder) recordRetry(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, err)
+}
+
+// snapshot returns the recorded attempt count and errors.
+func (r *attemptRecorder) snapshot() (int, []error) {
+	if r == nil {
+		return 0, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempts, append([]error(nil), r.errors...)
 }
 
 // DefaultRetryConfig returns a RetryConfig with sensible defaults:
 // - 3 maximum retries
 // - 1 second base backoff
+// - Server-requested retry delays capped at 1 minute
 // - Retries on network errors, 5xx status codes, and 429 (rate limit)
 //
 // Example:
@@ -32,30 +200,343 @@ type RetryConfig struct {
 //	config.MaxRetries = 5
 func DefaultRetryConfig() *RetryConfig {
 	return &RetryConfig{
-		MaxRetries: 3,
-		Backoff:    time.Second,
-		RetryIf:    defaultRetryCondition,
+		MaxRetries:   3,
+		Backoff:      time.Second,
+		RetryIf:      defaultRetryCondition,
+		MaxRetryWait: defaultMaxRetryWait,
 	}
 }
 
+// retryConfigKey is the context key under which a per-request RetryConfig override is stored.
+type retryConfigKey struct{}
+
+// withRetryOverride returns a copy of ctx carrying a per-request RetryConfig.
+func withRetryOverride(ctx context.Context, config *RetryConfig) context.Context {
+	return context.WithValue(ctx, retryConfigKey{}, config)
+}
+
+// retryOverride returns the per-request RetryConfig stored in ctx, if any.
+func retryOverride(ctx context.Context) (*RetryConfig, bool) {
+	config, ok := ctx.Value(retryConfigKey{}).(*RetryConfig)
+	return config, ok && config != nil
+}
+
+// idempotentMethods lists the HTTP methods that are idempotent per RFC 9110, section 9.2.2.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// isIdempotentMethod reports whether the HTTP method is idempotent.
+// An empty method means GET.
+func isIdempotentMethod(method string) bool {
+	return method == "" || idempotentMethods[method]
+}
+
+// canRetry reports whether req may be sent more than once.
+// Idempotent methods are always retryable; other methods are retryable when
+// RetryNonIdempotent is set or when the request carries an idempotency key.
+func (c *RetryConfig) canRetry(req *http.Request) bool {
+	if c.RetryNonIdempotent || isIdempotentMethod(req.Method) {
+		return true
+	}
+	if c.IdempotencyKeyHeader != "" && req.Header.Get(c.IdempotencyKeyHeader) != "" {
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// withIdempotencyKey returns req with a generated idempotency key attached when
+// IdempotencyKeyHeader is configured and req is a non-idempotent request without one.
+// The original request is never modified.
+func (c *RetryConfig) withIdempotencyKey(req *http.Request) *http.Request {
+	if c.IdempotencyKeyHeader == "" || isIdempotentMethod(req.Method) || req.Header.Get(c.IdempotencyKeyHeader) != "" {
+		return req
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set(c.IdempotencyKeyHeader, NewUUIDv4())
+	return req
+}
+
 // defaultRetryCondition returns true if the request should be retried.
-// It retries on any error, 5xx server errors, or 429 (rate limit) responses.
+// It retries on any error, 5xx server errors, or 429 (rate limit) responses,
+// except for requests rejected by an open circuit breaker.
 func defaultRetryCondition(resp *http.Response, err error) bool {
 	if err != nil {
-		return true
+		return !errors.Is(err, ErrCircuitOpen)
 	}
 
 	// Retry on 5xx errors and 429 (rate limit)
 	return resp.StatusCode >= 500 || resp.StatusCode == 429
 }
 
-// doRequest executes a single HTTP request without retry logic.
-// It wraps the standard http.Client.Do method and returns a Response.
+// backoffDelay returns the wait before the given zero-based retry attempt.
+// It uses BackoffStrategy when set and falls back to the linear Backoff schedule.
+func (c *RetryConfig) backoffDelay(attempt int, prev time.Duration) time.Duration {
+	if c.BackoffStrategy != nil {
+		return c.BackoffStrategy(attempt, prev)
+	}
+	return LinearBackoff(c.Backoff)(attempt, prev)
+}
+
+// retryDelay returns the wait before the given zero-based retry attempt.
+// A delay requested by the server through Retry-After or X-RateLimit-Reset
+// takes precedence over the backoff schedule and is capped at MaxRetryWait.
+func (c *RetryConfig) retryDelay(resp *http.Response, attempt int, prev time.Duration) time.Duration {
+	if wait, ok := serverRetryDelay(resp, time.Now()); ok {
+		maxWait := c.MaxRetryWait
+		if maxWait <= 0 {
+			maxWait = defaultMaxRetryWait
+		}
+		return min(wait, maxWait)
+	}
+	return c.backoffDelay(attempt, prev)
+}
+
+// serverRetryDelay extracts the delay requested by the server on a 429 or 503 response.
+// It understands Retry-After in both delay-seconds and HTTP-date form, and
+// X-RateLimit-Reset as either delta seconds or a Unix timestamp.
+// It returns false if the response carries no usable instruction.
+func serverRetryDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	if value := strings.TrimSpace(resp.Header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return nonNegative(time.Duration(seconds) * time.Second), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return nonNegative(date.Sub(now)), true
+		}
+	}
+
+	if value := strings.TrimSpace(resp.Header.Get("X-RateLimit-Reset")); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return rateLimitResetDelay(seconds, now), true
+		}
+	}
+
+	return 0, false
+}
+
+// unixTimestampThreshold separates X-RateLimit-Reset values expressed as a
+// Unix timestamp from those expressed as seconds until reset.
+// Any value above it (early 2001) is treated as a timestamp.
+const unixTimestampThreshold = 1e9
+
+// rateLimitResetDelay converts an X-RateLimit-Reset value to a wait duration.
+func rateLimitResetDelay(seconds float64, now time.Time) time.Duration {
+	if seconds > unixTimestampThreshold {
+		reset := time.Unix(0, int64(seconds*float64(time.Second)))
+		return nonNegative(reset.Sub(now))
+	}
+	return nonNegative(time.Duration(seconds * float64(time.Second)))
+}
+
+// nonNegative clamps negative durations to zero.
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// maxBufferedBodySize returns the effective body buffering limit.
+func (c *RetryConfig) maxBufferedBodySize() int64 {
+	if c.MaxBufferedBodySize == 0 {
+		return defaultMaxBufferedBodySize
+	}
+	return c.MaxBufferedBodySize
+}
+
+// hasBody reports whether req carries a request body.
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody
+}
+
+// isReplayable reports whether req can be sent again.
+func isReplayable(req *http.Request) bool {
+	return !hasBody(req) || req.GetBody != nil
+}
+
+// prepareRetryBody makes the body of req replayable for retries.
+// Requests without a body or with GetBody set are returned unchanged.
+// Otherwise up to limit bytes are buffered in memory and a copy of req with
+// GetBody set is returned. If the body is larger than limit, the copy streams
+// the buffered prefix followed by the rest of the body and is not replayable.
+// The caller's request is never modified.
+func prepareRetryBody(req *http.Request, limit int64) (*http.Request, error) {
+	if !hasBody(req) || req.GetBody != nil {
+		return req, nil
+	}
+
+	body := req.Body
+	req = req.WithContext(req.Context())
+
+	if limit < 0 {
+		return req, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		_ = body.Close()
+		return nil, fmt.Errorf("reading request body for retry: %w", err)
+	}
+
+	if int64(len(buf)) > limit {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), body), body}
+		return req, nil
+	}
+
+	_ = body.Close()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return req, nil
+}
+
+// rewindBody returns a shallow copy of req with a fresh body obtained from GetBody.
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if !hasBody(req) {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBodyNotReplayable, err)
+	}
+
+	req = req.WithContext(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+// sleepContext waits for the given duration or until ctx is done,
+// whichever comes first. It returns the context error if ctx ended the wait.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// maxDiscardBytes limits how much of a discarded response body is drained
+// so the underlying connection can be reused.
+const maxDiscardBytes = 4096
+
+// discardResponse drains and closes the body of a response that will not be
+// returned to the caller, allowing the connection to be reused.
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDiscardBytes))
+	_ = resp.Body.Close()
+}
+
+// cancelOnCloseBody releases an attempt context once the response body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the underlying body and cancels the attempt context.
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// doRequest executes a single HTTP request through the client transport chain.
+// It wraps the standard http.Client.Do method, runs the lifecycle hooks and
+// returns a Response carrying the attempt metadata collected by the retry transport.
 func (c *Client) doRequest(req *http.Request) (*Response, error) {
-	resp, err := c.httpClient.Do(req)
+	recorder := &attemptRecorder{}
+	timings := &timingAttempts{}
+	cache := &cacheRecorder{}
+	ctx := context.WithValue(req.Context(), attemptRecorderKey{}, recorder)
+	ctx = context.WithValue(ctx, timingAttemptsKey{}, timings)
+	ctx = context.WithValue(ctx, cacheRecorderKey{}, cache)
+	if c.redaction != nil {
+		ctx = context.WithValue(ctx, redactionKey{}, c.redaction)
+	}
+	if c.hooks != nil {
+		ctx = context.WithValue(ctx, hooksKey{}, c.hooks)
+	}
+	if c.tracing {
+		ctx = withRootTrace(ctx)
+	}
+
+	// Resolve the request ID once, so that every attempt and log line shares it
+	var requestID string
+	if c.requestID != nil {
+		requestID = c.requestID.resolve(req)
+		req.Header.Set(c.requestID.header, requestID)
+		ctx = ContextWithRequestID(ctx, requestID)
+	}
+	req = req.WithContext(ctx)
+
+	if err := c.hooks.request(req); err != nil {
+		c.hooks.error(req, err)
+		return nil, err
+	}
+
+	resp, err := c.retryClient(req).Do(req)
 	if err != nil {
+		c.hooks.error(req, err)
 		return nil, err
 	}
 
-	return &Response{Response: resp}, nil
+	attempts, errs := recorder.snapshot()
+
+	response := &Response{
+		Response:      resp,
+		attempts:      attempts,
+		attemptErrors: errs,
+		timings:       timings.recorderFor(resp),
+		requestID:     requestID,
+		fromCache:     cache.fromCache(),
+		revalidated:   cache.wasRevalidated(),
+	}
+	c.hooks.response(response)
+
+	return response, nil
+}
+
+// retryClient returns the http.Client used to send req. A per-request retry
+// override (see RequestBuilder.Retry) on a client without WithRetry is served
+// by an outermost retry transport added for that request only.
+func (c *Client) retryClient(req *http.Request) *http.Client {
+	if c.retry {
+		return c.httpClient
+	}
+	if _, ok := retryOverride(req.Context()); !ok {
+		return c.httpClient
+	}
+
+	httpClient := *c.httpClient
+	httpClient.Transport = &retryTransport{transport: c.httpClient.Transport}
+	return &httpClient
 }
@@ -4,9 +4,13 @@
 package httpc
 
 import (
+	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -363,3 +367,621 @@ func TestRetryConfig_ZeroBackoff(t *testing.T) {
 
 // This test is no longer applicable since interceptors are now transports
 // and don't return errors in the same way. Removing it.
+
+func TestServerRetryDelay(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		status   int
+		headers  map[string]string
+		expected time.Duration
+		ok       bool
+	}{
+		{"Retry-After seconds on 429", 429, map[string]string{"Retry-After": "3"}, 3 * time.Second, true},
+		{"Retry-After seconds on 503", 503, map[string]string{"Retry-After": "1"}, time.Second, true},
+		{"Retry-After HTTP-date", 429, map[string]string{"Retry-After": now.Add(5 * time.Second).Format(http.TimeFormat)}, 5 * time.Second, true},
+		{"Retry-After date in the past", 429, map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, 0, true},
+		{"X-RateLimit-Reset delta", 429, map[string]string{"X-RateLimit-Reset": "2"}, 2 * time.Second, true},
+		{"X-RateLimit-Reset timestamp", 429, map[string]string{"X-RateLimit-Reset": "1735732810"}, 10 * time.Second, true},
+		{"Retry-After wins over reset", 429, map[string]string{"Retry-After": "1", "X-RateLimit-Reset": "9"}, time.Second, true},
+		{"Invalid Retry-After", 429, map[string]string{"Retry-After": "soon"}, 0, false},
+		{"Ignored on 500", 500, map[string]string{"Retry-After": "3"}, 0, false},
+		{"No headers", 429, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+			for key, value := range tt.headers {
+				resp.Header.Set(key, value)
+			}
+
+			got, ok := serverRetryDelay(resp, now)
+			if ok != tt.ok {
+				t.Fatalf("serverRetryDelay() ok = %v, want %v", ok, tt.ok)
+			}
+			if got != tt.expected {
+				t.Errorf("serverRetryDelay() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryConfig_RetryDelay_CappedByMaxRetryWait(t *testing.T) {
+	config := RetryConfig{Backoff: time.Millisecond, MaxRetryWait: 2 * time.Second}
+	resp := &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": []string{"120"}}}
+
+	if got := config.retryDelay(resp, 0, 0); got != 2*time.Second {
+		t.Errorf("Expected delay capped at 2s, got %v", got)
+	}
+
+	// Without a server instruction, the backoff schedule is used
+	if got := config.retryDelay(&http.Response{StatusCode: 500}, 0, 0); got != time.Millisecond {
+		t.Errorf("Expected backoff delay 1ms, got %v", got)
+	}
+}
+
+func TestClient_WithRetry_HonorsRetryAfter(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if callCount.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := RetryConfig{
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}
+
+	client := NewClient(WithRetry(config))
+
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if elapsed < time.Second {
+		t.Errorf("Expected to wait at least 1s as instructed by Retry-After, took %v", elapsed)
+	}
+}
+
+func TestClient_WithRetry_ContextCancelAbortsBackoff(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	config := RetryConfig{
+		MaxRetries: 3,
+		Backoff:    10 * time.Second,
+		RetryIf:    defaultRetryCondition,
+	}
+
+	client := NewClient(WithRetry(config))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetWithContext(ctx, server.URL)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if elapsed > 2*time.Second {
+		t.Errorf("Expected backoff to abort on context cancellation, took %v", elapsed)
+	}
+
+	if callCount.Load() != 1 {
+		t.Errorf("Expected 1 call before cancellation, got %d", callCount.Load())
+	}
+}
+
+func TestClient_WithRetry_AttemptTimeout(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if callCount.Add(1) == 1 {
+			// First attempt hangs until the client gives up on it
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := RetryConfig{
+		MaxRetries:     2,
+		Backoff:        time.Millisecond,
+		AttemptTimeout: 100 * time.Millisecond,
+		RetryIf:        defaultRetryCondition,
+	}
+
+	client := NewClient(WithRetry(config))
+
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	body, err := resp.String()
+	if err != nil {
+		t.Fatalf("String() failed: %v", err)
+	}
+	if body != "ok" {
+		t.Errorf("Expected body 'ok', got %q", body)
+	}
+
+	if elapsed > 2*time.Second {
+		t.Errorf("Expected hung attempt to time out quickly, took %v", elapsed)
+	}
+
+	if callCount.Load() != 2 {
+		t.Errorf("Expected 2 calls, got %d", callCount.Load())
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	if err := sleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if err := sleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected sleep to return immediately for a cancelled context")
+	}
+}
+
+func TestRetryConfig_CanRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RetryConfig
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{"GET is retryable", RetryConfig{}, "GET", nil, true},
+		{"Empty method is GET", RetryConfig{}, "", nil, true},
+		{"PUT is retryable", RetryConfig{}, "PUT", nil, true},
+		{"DELETE is retryable", RetryConfig{}, "DELETE", nil, true},
+		{"POST is not retryable", RetryConfig{}, "POST", nil, false},
+		{"PATCH is not retryable", RetryConfig{}, "PATCH", nil, false},
+		{"POST with RetryNonIdempotent", RetryConfig{RetryNonIdempotent: true}, "POST", nil, true},
+		{"POST with Idempotency-Key", RetryConfig{}, "POST", map[string]string{"Idempotency-Key": "abc"}, true},
+		{"POST with custom key header", RetryConfig{IdempotencyKeyHeader: "X-Op-Id"}, "POST", map[string]string{"X-Op-Id": "abc"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "http://example.com", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			if got := tt.config.canRetry(req); got != tt.want {
+				t.Errorf("canRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_WithRetry_NoRetryForPost(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+
+	resp, err := client.Post(server.URL, map[string]string{"order": "1"})
+	if err != nil {
+		t.Fatalf("Post() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+
+	// POST is not idempotent, so it must not be retried by default
+	if callCount.Load() != 1 {
+		t.Errorf("Expected 1 call, got %d", callCount.Load())
+	}
+}
+
+func TestClient_WithRetry_IdempotencyKeyStableAcrossAttempts(t *testing.T) {
+	var callCount atomic.Int32
+	var mu sync.Mutex
+	var keys []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		if callCount.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries:           3,
+		Backoff:              time.Millisecond,
+		RetryIf:              defaultRetryCondition,
+		IdempotencyKeyHeader: "Idempotency-Key",
+	}))
+
+	resp, err := client.Post(server.URL, map[string]string{"order": "1"})
+	if err != nil {
+		t.Fatalf("Post() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", resp.StatusCode)
+	}
+
+	if callCount.Load() != 3 {
+		t.Fatalf("Expected 3 calls, got %d", callCount.Load())
+	}
+
+	// A second logical request gets a fresh key
+	if _, err := client.Post(server.URL, nil); err != nil {
+		t.Fatalf("Post() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if keys[0] == "" {
+		t.Fatal("Expected Idempotency-Key header to be generated")
+	}
+	for i, key := range keys[:3] {
+		if key != keys[0] {
+			t.Errorf("Attempt %d: expected key %q, got %q", i+1, keys[0], key)
+		}
+	}
+	if keys[3] == "" || keys[3] == keys[0] {
+		t.Errorf("Expected a new key for a new request, got %q", keys[3])
+	}
+}
+
+// streamReader hides the concrete reader type so http.NewRequest cannot set GetBody,
+// simulating a streamed upload.
+type streamReader struct {
+	io.Reader
+}
+
+func TestClient_WithRetry_ReplaysGetBody(t *testing.T) {
+	var callCount atomic.Int32
+	var mu sync.Mutex
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		if callCount.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+
+	resp, err := client.Put(server.URL, map[string]string{"name": "John"})
+	if err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, body := range bodies {
+		if body != `{"name":"John"}` {
+			t.Errorf("Attempt %d: expected full JSON body, got %q", i+1, body)
+		}
+	}
+}
+
+func TestClient_WithRetry_BuffersSmallStreamedBody(t *testing.T) {
+	var callCount atomic.Int32
+	var lastBody atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lastBody.Store(string(body))
+		if callCount.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+	}))
+
+	resp, err := client.NewRequest().
+		Method("PUT").
+		URL(server.URL).
+		Body(streamReader{strings.NewReader("streamed payload")}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if callCount.Load() != 2 {
+		t.Errorf("Expected 2 calls, got %d", callCount.Load())
+	}
+
+	if got := lastBody.Load(); got != "streamed payload" {
+		t.Errorf("Expected replayed body, got %q", got)
+	}
+}
+
+func TestClient_WithRetry_LargeStreamedBodyNotRetried(t *testing.T) {
+	var callCount atomic.Int32
+	var received atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		n, _ := io.Copy(io.Discard, r.Body)
+		received.Store(n)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries:          3,
+		Backoff:             time.Millisecond,
+		RetryIf:             defaultRetryCondition,
+		MaxBufferedBodySize: 8,
+	}))
+
+	payload := strings.Repeat("x", 64)
+	resp, err := client.NewRequest().
+		Method("PUT").
+		URL(server.URL).
+		Body(streamReader{strings.NewReader(payload)}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+
+	if callCount.Load() != 1 {
+		t.Errorf("Expected 1 call for a non-replayable body, got %d", callCount.Load())
+	}
+
+	// The whole body is still streamed on the first attempt
+	if received.Load() != int64(len(payload)) {
+		t.Errorf("Expected %d bytes received, got %d", len(payload), received.Load())
+	}
+}
+
+func TestClient_WithRetry_DefaultRetryIf(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if callCount.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// RetryIf is left unset
+	client := NewClient(WithRetry(RetryConfig{MaxRetries: 3, Backoff: time.Millisecond}))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || callCount.Load() != 3 {
+		t.Errorf("Expected the default condition to retry the 503s, got %d after %d calls", resp.StatusCode, callCount.Load())
+	}
+}
+
+func TestClient_WithRetry_NonRetryableBodyNotBuffered(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var buffered atomic.Bool
+	client := NewClient(
+		WithInterceptor(func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				buffered.Store(req.GetBody != nil)
+				return rt.RoundTrip(req)
+			})
+		}),
+		WithRetry(RetryConfig{
+			MaxRetries: 3,
+			Backoff:    time.Millisecond,
+			RetryIf:    defaultRetryCondition,
+		}),
+	)
+
+	resp, err := client.NewRequest().
+		Method("POST").
+		URL(server.URL).
+		Body(streamReader{strings.NewReader("payload")}).
+		Do()
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	if resp.Attempts() != 1 {
+		t.Errorf("Expected 1 attempt for a POST without idempotency key, got %d", resp.Attempts())
+	}
+	if buffered.Load() {
+		t.Error("Expected the body of a non-retryable request not to be buffered")
+	}
+}
+
+func TestClient_WithRetry_ErrBodyNotReplayable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serverURL := server.URL
+	server.Close() // Requests will fail with connection refused
+
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries:          3,
+		Backoff:             time.Millisecond,
+		RetryIf:             defaultRetryCondition,
+		MaxBufferedBodySize: -1,
+	}))
+
+	_, err := client.NewRequest().
+		Method("PUT").
+		URL(serverURL).
+		Body(streamReader{strings.NewReader("payload")}).
+		Do()
+
+	if !errors.Is(err, ErrBodyNotReplayable) {
+		t.Errorf("Expected ErrBodyNotReplayable, got %v", err)
+	}
+}
+
+func TestClient_WithRetry_OnRetryAndAttemptMetadata(t *testing.T) {
+	var callCount atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if callCount.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var events []RetryEvent
+	client := NewClient(WithRetry(RetryConfig{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		RetryIf:    defaultRetryCondition,
+		OnRetry: func(event RetryEvent) {
+			events = append(events, event)
+		},
+	}))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if resp.Attempts() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", resp.Attempts())
+	}
+
+	if len(resp.AttemptErrors()) != 2 {
+		t.Fatalf("Expected 2 attempt errors, got %d", len(resp.AttemptErrors()))
+	}
+
+	var httpErr *Error
+	if !errors.As(resp.AttemptErrors()[0], &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected *Error with status 502, got %v", resp.AttemptErrors()[0])
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 OnRetry events, got %d", len(events))
+	}
+
+	for i, event := range events {
+		if event.Attempt != i+1 {
+			t.Errorf("Event %d: expected attempt %d, got %d", i, i+1, event.Attempt)
+		}
+		if event.StatusCode != http.StatusBadGateway {
+			t.Errorf("Event %d: expected status 502, got %d", i, event.StatusCode)
+		}
+		if event.Delay != time.Duration(i+1)*time.Millisecond {
+			t.Errorf("Event %d: expected delay %v, got %v", i, time.Duration(i+1)*time.Millisecond, event.Delay)
+		}
+		if event.Request == nil {
+			t.Errorf("Event %d: expected request to be set", i)
+		}
+	}
+}
+
+func TestResponse_Attempts_WithoutRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if resp.Attempts() != 1 {
+		t.Errorf("Expected 1 attempt, got %d", resp.Attempts())
+	}
+
+	if len(resp.AttemptErrors()) != 0 {
+		t.Errorf("Expected no attempt errors, got %v", resp.AttemptErrors())
+	}
+}
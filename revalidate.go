@@ -0,0 +1,103 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the conditional revalidation transport, which replays
+// ETag and Last-Modified validators on repeated GET requests.
+package httpc
+
+import (
+	"net/http"
+	"time"
+)
+
+// RevalidationConfig configures WithRevalidation.
+type RevalidationConfig struct {
+	// Storage holds the last response with validators for each URL. If nil,
+	// an LRUCache of 1000 entries is used.
+	Storage CacheStorage
+
+	// MaxBodySize is the maximum size in bytes of a remembered response body.
+	// Larger responses are not remembered. If zero, 10 MB is used.
+	MaxBodySize int64
+}
+
+// revalidationTransport is an http.RoundTripper that turns repeated GET
+// requests into conditional requests.
+type revalidationTransport struct {
+	transport http.RoundTripper
+	config    RevalidationConfig
+	now       func() time.Time
+}
+
+// newRevalidationTransport creates a revalidationTransport, filling in
+// configuration defaults.
+func newRevalidationTransport(rt http.RoundTripper, config RevalidationConfig) *revalidationTransport {
+	if config.Storage == nil {
+		config.Storage = NewLRUCache(defaultCacheEntries)
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultCacheMaxBodySize
+	}
+	return &revalidationTransport{transport: rt, config: config, now: time.Now}
+}
+
+// RoundTrip implements http.RoundTripper. A GET request for a URL whose last
+// 200 response carried an ETag or Last-Modified header is sent with
+// If-None-Match or If-Modified-Since. A 304 Not Modified answer is replaced
+// by the remembered 200 response, with its headers updated from the 304.
+// Unlike WithCache, every request reaches the server.
+func (t *revalidationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.transport.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	entry := loadCacheEntry(t.config.Storage, key, req)
+
+	outReq := req
+	if entry != nil {
+		if conditional := conditionalRequest(req, entry.Header); conditional != nil {
+			outReq = conditional
+		}
+	}
+
+	requestTime := t.now()
+	resp, err := t.transport.RoundTrip(outReq)
+	if err != nil {
+		return resp, err
+	}
+	responseTime := t.now()
+
+	if outReq != req && resp.StatusCode == http.StatusNotModified {
+		discardResponse(resp)
+		entry.update(resp.Header, requestTime, responseTime)
+		storeCacheEntry(t.config.Storage, key, entry)
+		cacheRecorderFrom(req.Context()).recordRevalidated()
+		return entry.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	// Only a new 200 response with validators replaces the remembered one
+	if !hasValidators(resp.Header) || parseCacheControl(resp.Header).has("no-store") ||
+		resp.Body == nil || resp.ContentLength > t.config.MaxBodySize {
+		if entry != nil {
+			t.config.Storage.Delete(key)
+		}
+		return resp, nil
+	}
+
+	entry = &cacheEntry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+		Vary:         varyValues(req, resp.Header),
+	}
+	storeOnComplete(resp, t.config.MaxBodySize, func(body []byte) {
+		entry.Body = body
+		storeCacheEntry(t.config.Storage, key, entry)
+	})
+	return resp, nil
+}
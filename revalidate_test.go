@@ -0,0 +1,144 @@
+// Package httpc provides tests for conditional revalidation.
+// This file contains tests for the WithRevalidation transport and the
+// Response.Revalidated flag.
+package httpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WithRevalidation_ETag(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.Header().Set("X-Served", "revalidated")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"john"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRevalidation(RevalidationConfig{}))
+
+	resp, _ := getBody(t, client, server.URL)
+	if resp.Revalidated() {
+		t.Error("Expected the first response not to be revalidated")
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	var user struct {
+		Name string `json:"name"`
+	}
+	if err := resp.JSON(&user); err != nil || user.Name != "john" {
+		t.Errorf("Expected the remembered body to be decoded, got %+v (err %v)", user, err)
+	}
+	if resp.StatusCode != http.StatusOK || !resp.Revalidated() {
+		t.Errorf("Expected a revalidated 200, got %d (revalidated %v)", resp.StatusCode, resp.Revalidated())
+	}
+	if resp.FromCache() {
+		t.Error("Expected FromCache to be reserved for WithCache")
+	}
+	if resp.Header.Get("X-Served") != "revalidated" || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected headers merged from the 304, got %v", resp.Header)
+	}
+
+	// Every request reaches the server, even though the body is reused
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("Expected 2 requests with 1 Not Modified, got %d and %d", requests.Load(), notModified.Load())
+	}
+}
+
+func TestClient_WithRevalidation_LastModified(t *testing.T) {
+	lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	var version atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version.Load() == 0 {
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = w.Write([]byte("old"))
+			return
+		}
+		// The resource changed: a full response without validators
+		_, _ = w.Write([]byte("new"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRevalidation(RevalidationConfig{}))
+
+	getBody(t, client, server.URL)
+	if resp, body := getBody(t, client, server.URL); !resp.Revalidated() || body != "old" {
+		t.Errorf("Expected revalidated old body, got %q", body)
+	}
+
+	version.Store(1)
+	if resp, body := getBody(t, client, server.URL); resp.Revalidated() || body != "new" {
+		t.Errorf("Expected the new body, got %q", body)
+	}
+}
+
+func TestClient_WithRevalidation_Passthrough(t *testing.T) {
+	var conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			conditional.Add(1)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRevalidation(RevalidationConfig{}))
+
+	// POST responses are not remembered
+	if _, err := client.Post(server.URL, map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("Post() failed: %v", err)
+	}
+	getBody(t, client, server.URL)
+	if conditional.Load() != 0 {
+		t.Error("Expected the first GET not to be conditional")
+	}
+
+	// A caller's own conditional request is left untouched
+	resp, _ := getBody(t, client, server.URL, "If-None-Match", `"other"`)
+	if resp.Revalidated() || conditional.Load() != 1 {
+		t.Errorf("Expected caller conditional request to pass through, got %d", conditional.Load())
+	}
+}
+
+func TestClient_WithCache_Revalidated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithCache(CacheConfig{}))
+
+	getBody(t, client, server.URL)
+	resp, body := getBody(t, client, server.URL)
+	if !resp.Revalidated() || !resp.FromCache() || body != "payload" {
+		t.Errorf("Expected a revalidated cache hit, got %q (revalidated %v, from cache %v)",
+			body, resp.Revalidated(), resp.FromCache())
+	}
+}
@@ -0,0 +1,128 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the structured logging transport built on log/slog.
+package httpc
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// SlogConfig configures structured request logging with log/slog.
+type SlogConfig struct {
+	// Logger receives the log records. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// Level is the level of successful requests (status below 500).
+	// If nil, slog.LevelInfo is used.
+	Level slog.Leveler
+
+	// ErrorLevel is the level of failed requests: transport errors and 5xx
+	// responses. If nil, slog.LevelError is used.
+	ErrorLevel slog.Leveler
+
+	// SampleRate is the fraction of successful requests that are logged, from
+	// 0 to 1. Failed requests are always logged. If zero, every request is logged.
+	SampleRate float64
+
+	// RequestIDHeader is the header holding the request ID, read from the request
+	// and, failing that, from the response, when no ID was set by WithRequestId
+	// or ContextWithRequestID. If empty, "X-Request-Id" is used.
+	RequestIDHeader string
+
+	// Redaction masks sensitive query parameters in the logged URL. If nil, the
+	// Client policy set with WithRedaction is used, or DefaultRedactionPolicy.
+	Redaction *RedactionPolicy
+}
+
+// slogTransport is an http.RoundTripper that emits one structured log record
+// per request.
+type slogTransport struct {
+	transport http.RoundTripper
+	config    SlogConfig
+}
+
+// newSlogTransport creates a slogTransport, filling in configuration defaults.
+func newSlogTransport(rt http.RoundTripper, config SlogConfig) *slogTransport {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.Level == nil {
+		config.Level = slog.LevelInfo
+	}
+	if config.ErrorLevel == nil {
+		config.ErrorLevel = slog.LevelError
+	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = "X-Request-Id"
+	}
+
+	return &slogTransport{transport: rt, config: config}
+}
+
+// RoundTrip implements http.RoundTripper by sending the request and logging
+// its method, URL, status, duration, attempts, request ID and sizes.
+func (t *slogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+	duration := time.Since(start)
+
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	level := t.config.Level.Level()
+	if failed {
+		level = t.config.ErrorLevel.Level()
+	} else if !t.sampled() {
+		return resp, err
+	}
+
+	ctx := req.Context()
+	if !t.config.Logger.Enabled(ctx, level) {
+		return resp, err
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactionFrom(ctx, t.config.Redaction).RedactURL(req.URL)),
+		slog.Duration("duration", duration),
+	}
+
+	if attempts, _ := attemptRecorderFrom(ctx).snapshot(); attempts > 0 {
+		attrs = append(attrs, slog.Int("attempt", attempts))
+	}
+
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = req.Header.Get(t.config.RequestIDHeader)
+	}
+	if requestID == "" && resp != nil {
+		requestID = resp.Header.Get(t.config.RequestIDHeader)
+	}
+	if requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+
+	if req.ContentLength > 0 {
+		attrs = append(attrs, slog.Int64("request_size", req.ContentLength))
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		t.config.Logger.LogAttrs(ctx, level, "http request failed", attrs...)
+		return resp, err
+	}
+
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	if resp.ContentLength >= 0 {
+		attrs = append(attrs, slog.Int64("response_size", resp.ContentLength))
+	}
+
+	t.config.Logger.LogAttrs(ctx, level, "http request", attrs...)
+	return resp, nil
+}
+
+// sampled reports whether a successful request should be logged.
+func (t *slogTransport) sampled() bool {
+	rate := t.config.SampleRate
+	return rate <= 0 || rate >= 1 || rand.Float64() < rate
+}
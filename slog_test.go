@@ -0,0 +1,203 @@
+// Package httpc provides tests for structured logging.
+// This file contains tests for the log/slog transport, including levels,
+// attributes, request IDs, attempts, and sampling.
+package httpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// decodeLogRecords parses the JSON lines written by a slog.JSONHandler.
+func decodeLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestClient_WithSlog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := NewClient(
+		WithHeader("X-Request-Id", "req-123"),
+		WithSlog(SlogConfig{Logger: logger, Level: slog.LevelDebug}),
+	)
+
+	resp, err := client.Get(server.URL + "/users")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	records := decodeLogRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 log record, got %d", len(records))
+	}
+
+	record := records[0]
+	if record["level"] != "DEBUG" {
+		t.Errorf("Expected DEBUG level, got %v", record["level"])
+	}
+	if record["msg"] != "http request" {
+		t.Errorf("Expected message %q, got %v", "http request", record["msg"])
+	}
+	if record["method"] != http.MethodGet {
+		t.Errorf("Expected method GET, got %v", record["method"])
+	}
+	if record["url"] != server.URL+"/users" {
+		t.Errorf("Expected url %s/users, got %v", server.URL, record["url"])
+	}
+	if record["status"] != float64(http.StatusOK) {
+		t.Errorf("Expected status 200, got %v", record["status"])
+	}
+	if record["request_id"] != "req-123" {
+		t.Errorf("Expected request_id req-123, got %v", record["request_id"])
+	}
+	if record["response_size"] != float64(5) {
+		t.Errorf("Expected response_size 5, got %v", record["response_size"])
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Error("Expected a duration attribute")
+	}
+}
+
+func TestClient_WithSlog_ErrorLevel(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	config := DefaultRetryConfig()
+	config.MaxRetries = 2
+	config.Backoff = time.Millisecond
+
+	client := NewClient(
+		WithRetry(*config),
+		WithSlog(SlogConfig{Logger: logger}),
+	)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	records := decodeLogRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 log record, got %d", len(records))
+	}
+	if records[0]["level"] != "ERROR" {
+		t.Errorf("Expected ERROR level for a 5xx response, got %v", records[0]["level"])
+	}
+	if records[0]["attempt"] != float64(3) {
+		t.Errorf("Expected attempt 3, got %v", records[0]["attempt"])
+	}
+}
+
+func TestSlogTransport_TransportError(t *testing.T) {
+	var buf bytes.Buffer
+	transport := newSlogTransport(
+		roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}),
+		SlogConfig{Logger: slog.New(slog.NewJSONHandler(&buf, nil))},
+	)
+
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "http", Host: "api.example.com"}, Header: http.Header{}}
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	records := decodeLogRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 log record, got %d", len(records))
+	}
+	if records[0]["level"] != "ERROR" || records[0]["msg"] != "http request failed" {
+		t.Errorf("Expected an error record, got %v", records[0])
+	}
+	if records[0]["error"] != "connection refused" {
+		t.Errorf("Expected the error to be logged, got %v", records[0]["error"])
+	}
+}
+
+func TestSlogTransport_Sampling(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+
+	var buf bytes.Buffer
+	transport := newSlogTransport(
+		roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: int(status.Load()), Header: http.Header{}, ContentLength: -1}, nil
+		}),
+		SlogConfig{Logger: slog.New(slog.NewJSONHandler(&buf, nil)), SampleRate: 0.000001},
+	)
+
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "http", Host: "api.example.com"}, Header: http.Header{}}
+	for i := 0; i < 100; i++ {
+		_, _ = transport.RoundTrip(req)
+	}
+
+	if n := len(decodeLogRecords(t, &buf)); n > 1 {
+		t.Errorf("Expected successful requests to be sampled out, got %d records", n)
+	}
+	buf.Reset()
+
+	// Failed requests are always logged
+	status.Store(http.StatusInternalServerError)
+	for i := 0; i < 3; i++ {
+		_, _ = transport.RoundTrip(req)
+	}
+
+	if n := len(decodeLogRecords(t, &buf)); n != 3 {
+		t.Errorf("Expected every failed request to be logged, got %d records", n)
+	}
+}
+
+func TestSlogTransport_Defaults(t *testing.T) {
+	transport := newSlogTransport(defaultTransport(), SlogConfig{})
+
+	if transport.config.Logger != slog.Default() {
+		t.Error("Expected slog.Default() logger")
+	}
+	if transport.config.Level.Level() != slog.LevelInfo {
+		t.Errorf("Expected Info level, got %v", transport.config.Level)
+	}
+	if transport.config.ErrorLevel.Level() != slog.LevelError {
+		t.Errorf("Expected Error level, got %v", transport.config.ErrorLevel)
+	}
+	if transport.config.RequestIDHeader != "X-Request-Id" {
+		t.Errorf("Expected X-Request-Id header, got %q", transport.config.RequestIDHeader)
+	}
+}
@@ -0,0 +1,258 @@
+// Package httpc provides HTTP client functionality.
+// This file contains the timing transport, which breaks the duration of a
+// request down into phases using net/http/httptrace.
+package httpc
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings is the breakdown of the duration of a request into phases, as
+// measured by WithTimings. Phases that did not happen, such as DNS and connect
+// on a reused connection, are zero. When WithTimings is added before WithRetry
+// or WithHedging, each attempt is measured separately and the response reports
+// the timings of the attempt that produced it.
+type Timings struct {
+	// DNS is the duration of the DNS lookup
+	DNS time.Duration
+
+	// Connect is the duration of establishing the TCP connection
+	Connect time.Duration
+
+	// TLSHandshake is the duration of the TLS handshake
+	TLSHandshake time.Duration
+
+	// TimeToFirstByte is the time from the request being fully written to the
+	// first byte of the response. It approximates the server processing time
+	// plus one network round trip.
+	TimeToFirstByte time.Duration
+
+	// BodyRead is the time from the first response byte to the end of the body.
+	// It is only set once the body has been fully read or closed.
+	BodyRead time.Duration
+
+	// Total is the time from the start of the request to the end of the body,
+	// or to the first response byte while the body has not been read.
+	Total time.Duration
+
+	// ConnReused reports whether the request used a pooled connection
+	ConnReused bool
+}
+
+// timingAttemptsKey is the context key under which RequestBuilder.Do stores
+// the timingAttempts for a request.
+type timingAttemptsKey struct{}
+
+// timingAttempts collects a timingRecorder per attempt of a request, so that
+// concurrent attempts (see WithHedging) never overwrite each other's phases.
+// All methods are safe to call on a nil collector.
+type timingAttempts struct {
+	mu         sync.Mutex
+	recorders  map[*http.Response]*timingRecorder
+	lastRecord *timingRecorder
+}
+
+// timingAttemptsFrom returns the collector stored in ctx, or nil.
+func timingAttemptsFrom(ctx context.Context) *timingAttempts {
+	attempts, _ := ctx.Value(timingAttemptsKey{}).(*timingAttempts)
+	return attempts
+}
+
+// add records the recorder of the attempt that produced resp.
+func (a *timingAttempts) add(resp *http.Response, recorder *timingRecorder) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.recorders == nil {
+		a.recorders = make(map[*http.Response]*timingRecorder)
+	}
+	a.recorders[resp] = recorder
+	a.lastRecord = recorder
+}
+
+// recorderFor returns the recorder of the attempt that produced resp. When
+// resp was built by an outer transport, such as a cached response, the
+// recorder of the latest attempt is returned.
+func (a *timingAttempts) recorderFor(resp *http.Response) *timingRecorder {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if recorder, ok := a.recorders[resp]; ok {
+		return recorder
+	}
+	return a.lastRecord
+}
+
+// timingRecorder collects the timings of a single attempt from httptrace
+// hooks, which run on transport goroutines, and from the response body.
+// All methods are safe to call on a nil recorder.
+type timingRecorder struct {
+	mu      sync.Mutex
+	enabled bool
+	timings Timings
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wrote        time.Time
+	firstByte    time.Time
+}
+
+// record runs fn with the recorder locked, passing the current time.
+func (r *timingRecorder) record(fn func(now time.Time)) {
+	if r == nil {
+		return
+	}
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(now)
+}
+
+// begin starts measuring an attempt.
+func (r *timingRecorder) begin() {
+	r.record(func(now time.Time) {
+		r.enabled = true
+		r.timings = Timings{}
+		r.start = now
+		r.wrote = time.Time{}
+		r.firstByte = time.Time{}
+	})
+}
+
+// trace returns the httptrace hooks that feed the recorder.
+func (r *timingRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.record(func(now time.Time) { r.dnsStart = now })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.record(func(now time.Time) { r.timings.DNS = now.Sub(r.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			r.record(func(now time.Time) {
+				// Several addresses may be dialed; measure from the first one
+				if r.connectStart.Before(r.start) {
+					r.connectStart = now
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				r.record(func(now time.Time) { r.timings.Connect = now.Sub(r.connectStart) })
+			}
+		},
+		TLSHandshakeStart: func() {
+			r.record(func(now time.Time) { r.tlsStart = now })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.record(func(now time.Time) { r.timings.TLSHandshake = now.Sub(r.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.record(func(time.Time) { r.timings.ConnReused = info.Reused })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			r.record(func(now time.Time) { r.wrote = now })
+		},
+		GotFirstResponseByte: func() {
+			r.record(func(now time.Time) {
+				r.firstByte = now
+				if !r.wrote.IsZero() {
+					r.timings.TimeToFirstByte = now.Sub(r.wrote)
+				}
+				r.timings.Total = now.Sub(r.start)
+			})
+		},
+	}
+}
+
+// finish records the end of the response body.
+func (r *timingRecorder) finish() {
+	r.record(func(now time.Time) {
+		if !r.firstByte.IsZero() {
+			r.timings.BodyRead = now.Sub(r.firstByte)
+		}
+		r.timings.Total = now.Sub(r.start)
+	})
+}
+
+// snapshot returns the recorded timings, or nil if timing was not enabled.
+func (r *timingRecorder) snapshot() *Timings {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.enabled {
+		return nil
+	}
+	timings := r.timings
+	return &timings
+}
+
+// timingTransport is an http.RoundTripper that attaches httptrace hooks to
+// each request and records the phase timings for Response.Timings.
+type timingTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper by tracing the request with a
+// recorder of its own and timing the response body until it is closed or
+// fully read.
+func (t *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := timingAttemptsFrom(req.Context())
+	if attempts == nil {
+		return t.transport.RoundTrip(req)
+	}
+
+	recorder := &timingRecorder{}
+	recorder.begin()
+	ctx := httptrace.WithClientTrace(req.Context(), recorder.trace())
+
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return resp, err
+	}
+	attempts.add(resp, recorder)
+	if resp.Body == nil {
+		return resp, nil
+	}
+
+	resp.Body = &timingBody{ReadCloser: resp.Body, recorder: recorder}
+	return resp, nil
+}
+
+// timingBody records the end of the body once, when it is closed or fully read.
+type timingBody struct {
+	io.ReadCloser
+	recorder *timingRecorder
+	once     sync.Once
+}
+
+// Read reads from the underlying body and records the end of the body at EOF.
+func (b *timingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.recorder.finish)
+	}
+	return n, err
+}
+
+// Close closes the underlying body and records the end of the body.
+func (b *timingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.recorder.finish)
+	return err
+}
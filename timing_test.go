@@ -0,0 +1,168 @@
+// Package httpc provides tests for request phase timings.
+// This file contains tests for the httptrace-based timing transport and
+// Response.Timings.
+package httpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WithTimings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimings())
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if _, err := resp.Bytes(); err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+
+	timings := resp.Timings()
+	if timings == nil {
+		t.Fatal("Expected timings to be recorded")
+	}
+	if timings.Connect <= 0 {
+		t.Errorf("Expected a connect duration for a new connection, got %v", timings.Connect)
+	}
+	if timings.ConnReused {
+		t.Error("Expected the first request to use a new connection")
+	}
+	if timings.TimeToFirstByte < 20*time.Millisecond {
+		t.Errorf("Expected time to first byte to include server time, got %v", timings.TimeToFirstByte)
+	}
+	if timings.BodyRead < 20*time.Millisecond {
+		t.Errorf("Expected body read to include the streamed body, got %v", timings.BodyRead)
+	}
+	if timings.Total < timings.TimeToFirstByte+timings.BodyRead {
+		t.Errorf("Expected total %v to cover all phases", timings.Total)
+	}
+
+	// The second request reuses the pooled connection
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	if timings := resp.Timings(); !timings.ConnReused || timings.Connect != 0 {
+		t.Errorf("Expected a reused connection without connect time, got %+v", timings)
+	}
+}
+
+func TestClient_WithTimings_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithInterceptor(func(http.RoundTripper) http.RoundTripper {
+			return server.Client().Transport
+		}),
+		WithTimings(),
+	)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	if timings := resp.Timings(); timings == nil || timings.TLSHandshake <= 0 {
+		t.Errorf("Expected a TLS handshake duration, got %+v", timings)
+	}
+}
+
+func TestClient_WithTimings_Retry(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			time.Sleep(30 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := DefaultRetryConfig()
+	config.Backoff = time.Millisecond
+
+	client := NewClient(WithTimings(), WithRetry(*config))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	if timings := resp.Timings(); timings.TimeToFirstByte >= 30*time.Millisecond {
+		t.Errorf("Expected the timings of the final attempt, got %+v", timings)
+	}
+}
+
+func TestClient_WithTimings_Hedging(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay := 60 * time.Millisecond
+		if calls.Add(1) > 1 {
+			delay = time.Second
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimings(), WithHedging(HedgeConfig{Delay: 20 * time.Millisecond}))
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	_, _ = resp.Bytes()
+
+	// The hedge starts 20ms in; it must not reset the winning copy's clock
+	if timings := resp.Timings(); timings.TimeToFirstByte < 60*time.Millisecond {
+		t.Errorf("Expected the timings of the winning copy, got %+v", timings)
+	}
+}
+
+func TestResponse_Timings_Disabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if timings := resp.Timings(); timings != nil {
+		t.Errorf("Expected no timings without WithTimings, got %+v", timings)
+	}
+
+	if timings := (&Response{}).Timings(); timings != nil {
+		t.Errorf("Expected no timings on a bare Response, got %+v", timings)
+	}
+}
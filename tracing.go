@@ -0,0 +1,348 @@
+// Package httpc provides HTTP client functionality.
+// This file contains W3C Trace Context propagation and the client span
+// tracing transport.
+package httpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID is a W3C Trace Context trace identifier.
+type TraceID [16]byte
+
+// String returns the trace ID as 32 lowercase hex characters.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the trace ID is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID is a W3C Trace Context span (parent) identifier.
+type SpanID [8]byte
+
+// String returns the span ID as 16 lowercase hex characters.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the span ID is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext identifies a span within a trace, as carried by the W3C
+// traceparent and tracestate headers.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+
+	// Sampled is the sampled flag of the trace. Spans of unsampled traces are
+	// propagated but not exported.
+	Sampled bool
+
+	// TraceState is the vendor-specific tracestate header value, propagated as is.
+	TraceState string
+}
+
+// IsValid reports whether both the trace ID and the span ID are valid.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent returns the span context as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value, such as one
+// received by a server, into a SpanContext.
+//
+// Example:
+//
+//	sc, err := httpc.ParseTraceparent(r.Header.Get("traceparent"))
+//	if err == nil {
+//		sc.TraceState = r.Header.Get("tracestate")
+//		ctx = httpc.ContextWithSpanContext(ctx, sc)
+//	}
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+
+	var sc SpanContext
+	var version, flags [1]byte
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 ||
+		!decodeLowerHex(version[:], parts[0]) ||
+		!decodeLowerHex(sc.TraceID[:], parts[1]) ||
+		!decodeLowerHex(sc.SpanID[:], parts[2]) ||
+		!decodeLowerHex(flags[:], parts[3]) ||
+		!sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+	sc.Sampled = flags[0]&0x01 != 0
+
+	return sc, nil
+}
+
+// decodeLowerHex decodes the lowercase hex string s into dst.
+func decodeLowerHex(dst []byte, s string) bool {
+	if strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// spanContextKey is the context key under which ContextWithSpanContext stores
+// the parent span context.
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying sc as the parent of
+// the client spans of requests made with it (see WithTracing).
+//
+// Example:
+//
+//	ctx := httpc.ContextWithSpanContext(r.Context(), sc)
+//	resp, err := client.NewRequest().Context(ctx).URL("/users").Do()
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context stored in ctx by
+// ContextWithSpanContext, if any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// rootTraceKey is the context key under which RequestBuilder.Do stores the
+// TraceID shared by the attempts of a request that has no parent span.
+type rootTraceKey struct{}
+
+// withRootTrace returns ctx carrying a new root TraceID, unless it already
+// carries a parent span context.
+func withRootTrace(ctx context.Context) context.Context {
+	if parent, ok := SpanContextFromContext(ctx); ok && parent.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, rootTraceKey{}, newTraceID())
+}
+
+// Span is a finished client span, recorded for each attempt of a request.
+type Span struct {
+	// Name is the span name, the HTTP method of the request.
+	Name string
+
+	// SpanContext identifies the span. It is the context propagated in the
+	// traceparent header of the attempt.
+	SpanContext SpanContext
+
+	// Parent is the span context found in the request context, or the zero
+	// value for a root span.
+	Parent SpanContext
+
+	// Method, URL and Host describe the request. Sensitive query parameters are
+	// masked in URL according to the Client redaction policy.
+	Method string
+	URL    string
+	Host   string
+
+	// Attempt is the 1-based number of the attempt, greater than 1 for retries.
+	Attempt int
+
+	// StatusCode is the response status, or 0 if the attempt failed without a response.
+	StatusCode int
+
+	// Err is the transport error of the attempt, if any.
+	Err error
+
+	// Start and End are the times the attempt was sent and its response
+	// headers were received.
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the duration of the span.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// SpanExporter receives the client spans of sampled traces recorded by
+// WithTracing. Implementations must be safe for concurrent use; an adapter
+// can forward spans to a tracing backend.
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+// TracingConfig configures WithTracing.
+type TracingConfig struct {
+	// Exporter receives the recorded spans. If nil, trace context is
+	// propagated but no spans are recorded.
+	Exporter SpanExporter
+
+	// B3 additionally injects the Zipkin B3 multi-header format
+	// (X-B3-TraceId, X-B3-SpanId, X-B3-ParentSpanId and X-B3-Sampled).
+	B3 bool
+}
+
+// tracingTransport is an http.RoundTripper that propagates trace context and
+// records a client span per attempt.
+type tracingTransport struct {
+	transport http.RoundTripper
+	config    TracingConfig
+}
+
+// RoundTrip implements http.RoundTripper by starting a client span, injecting
+// its context into the request headers and exporting the span once the
+// response headers are received. The span is a child of the span context in
+// the request context; without one, it joins the trace started for the
+// request by RequestBuilder.Do, so that all attempts share one TraceID.
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	parent, _ := SpanContextFromContext(ctx)
+	sc := SpanContext{SpanID: newSpanID(), Sampled: true}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		sc.TraceState = parent.TraceState
+	} else if root, ok := ctx.Value(rootTraceKey{}).(TraceID); ok {
+		parent = SpanContext{}
+		sc.TraceID = root
+	} else {
+		parent = SpanContext{}
+		sc.TraceID = newTraceID()
+	}
+
+	req = req.Clone(ctx)
+	t.inject(req.Header, sc, parent)
+
+	attempts, _ := attemptRecorderFrom(ctx).snapshot()
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+
+	if t.config.Exporter == nil || !sc.Sampled {
+		return resp, err
+	}
+
+	span := Span{
+		Name:        req.Method,
+		SpanContext: sc,
+		Parent:      parent,
+		Method:      req.Method,
+		URL:         redactionFrom(ctx, nil).RedactURL(req.URL),
+		Host:        req.URL.Host,
+		Attempt:     attempts + 1,
+		Err:         err,
+		Start:       start,
+		End:         time.Now(),
+	}
+	if resp != nil {
+		span.StatusCode = resp.StatusCode
+	}
+	t.config.Exporter.ExportSpan(span)
+
+	return resp, err
+}
+
+// inject sets the propagation headers of sc, replacing any left by a previous attempt.
+func (t *tracingTransport) inject(header http.Header, sc, parent SpanContext) {
+	header.Set("traceparent", sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set("tracestate", sc.TraceState)
+	} else {
+		header.Del("tracestate")
+	}
+
+	if !t.config.B3 {
+		return
+	}
+	header.Set("X-B3-TraceId", sc.TraceID.String())
+	header.Set("X-B3-SpanId", sc.SpanID.String())
+	if parent.IsValid() {
+		header.Set("X-B3-ParentSpanId", parent.SpanID.String())
+	} else {
+		header.Del("X-B3-ParentSpanId")
+	}
+	if sc.Sampled {
+		header.Set("X-B3-Sampled", "1")
+	} else {
+		header.Set("X-B3-Sampled", "0")
+	}
+}
+
+// newTraceID returns a random, valid trace ID.
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+// newSpanID returns a random, valid span ID.
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+// InMemoryExporter is a SpanExporter that keeps spans in memory, so that they
+// can be asserted in tests without a collector. It is safe for concurrent use.
+//
+// Example:
+//
+//	exporter := httpc.NewInMemoryExporter()
+//	client := httpc.NewClient(httpc.WithTracing(httpc.TracingConfig{Exporter: exporter}))
+//	// ...
+//	for _, span := range exporter.Spans() {
+//		fmt.Println(span.Name, span.URL, span.StatusCode, span.Attempt)
+//	}
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewInMemoryExporter creates an empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan implements SpanExporter.
+func (e *InMemoryExporter) ExportSpan(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns a copy of the exported spans, in the order they ended.
+func (e *InMemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Span(nil), e.spans...)
+}
+
+// Reset discards all exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
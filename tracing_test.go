@@ -0,0 +1,235 @@
+// Package httpc provides tests for trace context propagation.
+// This file contains tests for traceparent parsing, header injection and
+// client spans recorded by WithTracing.
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ParseTraceparent() failed: %v", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Errorf("Unexpected span context %+v", sc)
+	}
+	if sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("Expected round trip of traceparent, got %q", sc.Traceparent())
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, value := range invalid {
+		if _, err := ParseTraceparent(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("Expected future versions to allow extra fields, got %v", err)
+	}
+}
+
+func TestClient_WithTracing_Propagation(t *testing.T) {
+	var mu sync.Mutex
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = r.Header.Clone()
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter := NewInMemoryExporter()
+	client := NewClient(WithTracing(TracingConfig{Exporter: exporter, B3: true}))
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.TraceState = "vendor=value"
+	ctx := ContextWithSpanContext(context.Background(), parent)
+
+	if _, err := client.NewRequest().Context(ctx).URL(server.URL + "/users").Do(); err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.SpanContext.TraceID != parent.TraceID || span.Parent.SpanID != parent.SpanID {
+		t.Errorf("Expected span to be a child of the context span, got %+v", span)
+	}
+	if span.SpanContext.SpanID == parent.SpanID {
+		t.Error("Expected a new span ID")
+	}
+	if span.Name != http.MethodGet || span.StatusCode != http.StatusOK || span.Attempt != 1 || span.Duration() <= 0 {
+		t.Errorf("Unexpected span %+v", span)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := received.Get("traceparent"); got != span.SpanContext.Traceparent() {
+		t.Errorf("Expected traceparent %q, got %q", span.SpanContext.Traceparent(), got)
+	}
+	if got := received.Get("tracestate"); got != "vendor=value" {
+		t.Errorf("Expected tracestate to be propagated, got %q", got)
+	}
+	if received.Get("X-B3-TraceId") != parent.TraceID.String() ||
+		received.Get("X-B3-SpanId") != span.SpanContext.SpanID.String() ||
+		received.Get("X-B3-ParentSpanId") != parent.SpanID.String() ||
+		received.Get("X-B3-Sampled") != "1" {
+		t.Errorf("Unexpected B3 headers %v", received)
+	}
+}
+
+func TestClient_WithTracing_RootAndUnsampled(t *testing.T) {
+	var traceparent atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("traceparent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter := NewInMemoryExporter()
+	client := NewClient(WithTracing(TracingConfig{Exporter: exporter}))
+
+	// Without a parent, a new sampled trace is started
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	spans := exporter.Spans()
+	if len(spans) != 1 || !spans[0].SpanContext.IsValid() || spans[0].Parent.IsValid() {
+		t.Fatalf("Expected a root span, got %+v", spans)
+	}
+
+	// An unsampled parent is propagated but not exported
+	exporter.Reset()
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx := ContextWithSpanContext(context.Background(), parent)
+	if _, err := client.NewRequest().Context(ctx).URL(server.URL).Do(); err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	if len(exporter.Spans()) != 0 {
+		t.Errorf("Expected no spans for an unsampled trace, got %d", len(exporter.Spans()))
+	}
+	sc, err := ParseTraceparent(traceparent.Load().(string))
+	if err != nil || sc.TraceID != parent.TraceID || sc.Sampled {
+		t.Errorf("Expected unsampled traceparent in the trace, got %q", traceparent.Load())
+	}
+}
+
+func TestClient_WithTracing_Retries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter := NewInMemoryExporter()
+	client := NewClient(
+		WithTracing(TracingConfig{Exporter: exporter}),
+		WithRetry(RetryConfig{
+			MaxRetries: 3,
+			Backoff:    time.Millisecond,
+			RetryIf:    defaultRetryCondition,
+		}),
+	)
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithSpanContext(context.Background(), parent)
+	if _, err := client.NewRequest().Context(ctx).URL(server.URL).Do(); err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected a span per attempt, got %d", len(spans))
+	}
+
+	seen := make(map[SpanID]bool)
+	for i, span := range spans {
+		if span.Attempt != i+1 {
+			t.Errorf("Expected attempt %d, got %d", i+1, span.Attempt)
+		}
+		if span.SpanContext.TraceID != parent.TraceID {
+			t.Errorf("Expected attempt %d in the parent trace", span.Attempt)
+		}
+		if seen[span.SpanContext.SpanID] {
+			t.Errorf("Expected a distinct span ID for attempt %d", span.Attempt)
+		}
+		seen[span.SpanContext.SpanID] = true
+	}
+	if spans[0].StatusCode != http.StatusServiceUnavailable || spans[2].StatusCode != http.StatusOK {
+		t.Errorf("Unexpected status codes %d, %d", spans[0].StatusCode, spans[2].StatusCode)
+	}
+}
+
+func TestClient_WithTracing_RetriesWithoutParent(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter := NewInMemoryExporter()
+	client := NewClient(
+		WithTracing(TracingConfig{Exporter: exporter}),
+		WithRetry(RetryConfig{
+			MaxRetries: 3,
+			Backoff:    time.Millisecond,
+			RetryIf:    defaultRetryCondition,
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		calls.Store(0)
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 6 {
+		t.Fatalf("Expected a span per attempt, got %d", len(spans))
+	}
+
+	// The attempts of one request share a trace; each request starts its own
+	for _, request := range [][]Span{spans[:3], spans[3:]} {
+		for _, span := range request {
+			if span.SpanContext.TraceID != request[0].SpanContext.TraceID {
+				t.Errorf("Expected attempt %d in the trace of the request", span.Attempt)
+			}
+			if span.Parent.IsValid() {
+				t.Errorf("Expected attempt %d to be a root span, got parent %v", span.Attempt, span.Parent)
+			}
+		}
+	}
+	if spans[0].SpanContext.TraceID == spans[3].SpanContext.TraceID {
+		t.Error("Expected separate requests to start separate traces")
+	}
+}
@@ -4,8 +4,8 @@
 package httpc
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"net/http"
@@ -112,54 +112,145 @@ func (t *blockListTransport) RoundTrip(req *http.Request) (*http.Response, error
 }
 
 // retryTransport is an http.RoundTripper that implements automatic retry logic
-// with exponential backoff. It retries failed requests based on the configured
-// RetryConfig, which specifies max retries, backoff duration, and retry conditions.
+// with a configurable backoff strategy. It retries failed requests based on the configured
+// RetryConfig, which specifies max retries, backoff schedule, and retry conditions.
 type retryTransport struct {
 	transport http.RoundTripper
 	config    RetryConfig
+	budget    *retryBudget
 }
 
-// RoundTrip implements http.RoundTripper by adding retry logic with exponential backoff.
-// It preserves the request body across retries by caching it in memory.
-// The backoff duration increases exponentially: backoff * (attempt + 1).
+// RoundTrip implements http.RoundTripper by adding retry logic with backoff.
+// Request bodies are replayed on each attempt through http.Request.GetBody
+// when available; otherwise bodies up to RetryConfig.MaxBufferedBodySize are
+// buffered in memory (see prepareRetryBody). Bodies of requests that cannot be
+// retried, such as a POST without an idempotency key, are streamed unbuffered.
+// The wait between attempts is computed by RetryConfig.BackoffStrategy,
+// defaulting to a linear schedule: backoff * (attempt + 1). A Retry-After or
+// X-RateLimit-Reset header on a 429 or 503 response overrides the schedule.
+// Waits are aborted as soon as the request context is done, in which case
+// the context error is returned. Non-idempotent requests are only retried
+// when RetryConfig allows it (see RetryNonIdempotent and IdempotencyKeyHeader).
+// A RetryConfig attached to the request context by RequestBuilder.Retry or
+// RequestBuilder.NoRetry replaces the client configuration for that request.
 func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
+	ctx := req.Context()
+	config := t.config
+	if override, ok := retryOverride(ctx); ok {
+		config = *override
+	}
+	if config.RetryIf == nil {
+		config.RetryIf = defaultRetryCondition
+	}
 
-	var bodyBytes []byte
+	if t.budget != nil {
+		t.budget.recordRequest()
+	}
+
+	recorder := attemptRecorderFrom(ctx)
+	recorder.reset()
 
-	if req.Body != nil {
-		bodyBytes, _ = io.ReadAll(req.Body)
-		_ = req.Body.Close()
+	// Nothing to retry, so skip the body and idempotency bookkeeping
+	if config.MaxRetries <= 0 {
+		recorder.recordAttempt()
+		return t.roundTripAttempt(req, config.AttemptTimeout)
 	}
 
-	for attempt := 0; attempt <= t.config.MaxRetries; attempt++ {
-		if bodyBytes != nil {
-			req.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
+	req = config.withIdempotencyKey(req)
+	retryable := config.canRetry(req)
+
+	// Only bodies that may be sent again are worth buffering
+	var err error
+	if retryable {
+		if req, err = prepareRetryBody(req, config.maxBufferedBodySize()); err != nil {
+			return nil, err
 		}
+	}
+
+	var resp *http.Response
+	var delay time.Duration
 
-		resp, err = t.transport.RoundTrip(req)
+	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			if attemptReq, err = rewindBody(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = t.roundTripAttempt(attemptReq, config.AttemptTimeout)
+		recorder.recordAttempt()
+
+		// The caller gave up, so there is nothing left to retry for
+		if ctx.Err() != nil {
+			break
+		}
 
 		// Check if we should retry
-		shouldRetry := t.config.RetryIf != nil && t.config.RetryIf(resp, err)
+		shouldRetry := retryable && config.RetryIf(resp, err)
+		if !shouldRetry || attempt >= config.MaxRetries {
+			break
+		}
+
+		// A streamed body has already been consumed and cannot be sent again.
+		// A response is returned as is, see ErrBodyNotReplayable.
+		if !isReplayable(req) {
+			if err != nil {
+				err = fmt.Errorf("%w: %w", ErrBodyNotReplayable, err)
+			}
+			break
+		}
+
+		if !t.allowRetry() {
+			break
+		}
 
-		// If this is not the last attempt and we should retry, wait and continue
-		if attempt < t.config.MaxRetries && shouldRetry {
-			// Exponential backoff: backoff * (attempt + 1)
-			time.Sleep(t.config.Backoff * time.Duration(attempt+1))
-			continue
+		delay = config.retryDelay(resp, attempt, delay)
+		event := newRetryEvent(req, attempt+1, resp, err, delay)
+		recorder.recordRetry(event.Err)
+		if config.OnRetry != nil {
+			config.OnRetry(event)
 		}
+		hooksFrom(ctx).retry(event)
 
-		// Either last attempt or shouldn't retry, so return
-		break
+		discardResponse(resp)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
 
 	return resp, err
 }
 
+// allowRetry reports whether the shared retry budget permits another retry.
+// Without a budget, retries are only limited by RetryConfig.MaxRetries.
+func (t *retryTransport) allowRetry() bool {
+	return t.budget == nil || t.budget.tryRetry()
+}
+
+// roundTripAttempt sends a single attempt, bounded by timeout (RetryConfig.AttemptTimeout) when set.
+// The attempt context stays alive until the response body is closed, so the
+// caller can still read a successful response.
+func (t *retryTransport) roundTripAttempt(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return t.transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil || resp == nil || resp.Body == nil {
+		cancel()
+		return resp, err
+	}
+
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
 // loggingTransport is an http.RoundTripper that logs HTTP requests and responses.
 // It logs the request method, URL, response status code, and timing information
-// using the configured logger.
+// using the configured logger. Sensitive query parameters are masked according to
+// the Client redaction policy (see WithRedaction).
 type loggingTransport struct {
 	transport http.RoundTripper
 	logger    *log.Logger
@@ -169,19 +260,18 @@ type loggingTransport struct {
 func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	start := time.Now()
 
-	t.logger.Printf("→ %s %s", req.Method, req.URL.String())
+	policy := redactionFrom(req.Context(), nil)
+	id := requestIDSuffix(req.Context())
+	t.logger.Printf("→ %s %s%s", req.Method, policy.RedactURL(req.URL), id)
 
 	resp, err := t.transport.RoundTrip(req)
 	duration := time.Since(start)
 	if err != nil {
-		t.logger.Printf("← Error: %v (took %v)", err, duration)
+		t.logger.Printf("← Error: %v (took %v)%s", err, duration, id)
 	} else {
 
-		t.logger.Printf("← %d (took %v)", resp.StatusCode, duration)
+		t.logger.Printf("← %d (took %v)%s", resp.StatusCode, duration, id)
 	}
 
 	return resp, err
 }
-
-//TODO add metrics transport
-//TODO add rate limiting transport